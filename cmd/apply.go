@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// applyTolerance is how far short of the manifest's TTL a session may be
+// before apply extends it, so re-applying right away changes nothing.
+const applyTolerance = time.Minute
+
+// Plan actions of 'trek session apply'.
+const (
+	applyCreate    = "create"
+	applyExtend    = "extend"
+	applyUnchanged = "unchanged"
+)
+
+var (
+	applyFile   string
+	applyYes    bool
+	applyDryRun bool
+)
+
+var sessionApplyCmd = &cobra.Command{
+	Use:   "apply -f <file>",
+	Short: "Create or extend sessions to match a manifest",
+	Long: `Create or extend debug sessions to match a YAML or JSON manifest. A file
+may hold several YAML documents, one session each; use -f - for stdin.
+
+An active session with the same selector and level counts as the manifest's
+session: it is extended if it would expire before the manifest's ttl from
+now, and left alone otherwise. Labels, reason and caps apply to new
+sessions only. The plan is printed and confirmed before anything changes.
+
+Manifest:
+  selector:
+    user_id: u123
+    route: /api/orders*
+    custom:
+      region: eu-west-1
+  level: debug          # or trace (default debug)
+  ttl: 30m
+  reason: investigating checkout failures
+  labels:
+    ticket: TREK-42
+  caps:
+    max_debug_events_per_request: 100
+    max_debug_events_per_session: 5000
+
+Examples:
+  trek session apply -f debug.yaml
+  trek session apply -f debug.yaml --dry-run
+  cat debug.yaml | trek session apply -f - --yes`,
+	Args: cobra.NoArgs,
+	RunE: runApply,
+}
+
+func init() {
+	sessionCmd.AddCommand(sessionApplyCmd)
+
+	sessionApplyCmd.Flags().StringVarP(&applyFile, "filename", "f", "", "Manifest file, or - for stdin")
+	sessionApplyCmd.Flags().BoolVarP(&applyYes, "yes", "y", false, "Apply without confirmation")
+	sessionApplyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Print the plan without changing anything")
+}
+
+// sessionManifest is one document of a manifest file.
+type sessionManifest struct {
+	Selector selectorView      `yaml:"selector"`
+	Level    string            `yaml:"level"`
+	TTL      string            `yaml:"ttl"`
+	Reason   string            `yaml:"reason"`
+	Labels   map[string]string `yaml:"labels"`
+	Caps     *capsView         `yaml:"caps"`
+
+	// Source is the file and document number, for messages.
+	Source string `yaml:"-"`
+	ttl    time.Duration
+}
+
+// readManifests parses every document in data. name is used in errors.
+func readManifests(name string, data []byte) ([]sessionManifest, error) {
+	var manifests []sessionManifest
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	for doc := 1; ; doc++ {
+		var m sessionManifest
+		err := dec.Decode(&m)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		source := fmt.Sprintf("%s#%d", name, doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+		}
+		m.Source = source
+		if err := m.validate(); err != nil {
+			return nil, fmt.Errorf("invalid manifest %s: %w", source, err)
+		}
+		manifests = append(manifests, m)
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("%s has no session manifests", name)
+	}
+	return manifests, nil
+}
+
+func (m *sessionManifest) validate() error {
+	if trek.IsEmptySelector(trek.Selector(m.Selector)) {
+		return errors.New("selector needs at least one of user_id, request_id, tenant_id, route or custom")
+	}
+	if m.Level == "" {
+		m.Level = string(trek.LevelDebug)
+	}
+	if m.Level != string(trek.LevelDebug) && m.Level != string(trek.LevelTrace) {
+		return fmt.Errorf("level must be debug or trace, got %q", m.Level)
+	}
+	if m.TTL == "" {
+		return errors.New("ttl is required")
+	}
+	d, err := time.ParseDuration(m.TTL)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid ttl %q: expected a positive duration such as 30m", m.TTL)
+	}
+	m.ttl = d
+	if m.Caps != nil && (m.Caps.MaxDebugEventsPerRequest < 0 || m.Caps.MaxDebugEventsPerSession < 0) {
+		return errors.New("caps must not be negative")
+	}
+	return nil
+}
+
+// matches reports whether s is the session m describes.
+func (m sessionManifest) matches(s trek.Session) bool {
+	sel := trek.Selector(m.Selector)
+	return string(s.Level) == m.Level &&
+		s.Selector.UserID == sel.UserID &&
+		s.Selector.RequestID == sel.RequestID &&
+		s.Selector.TenantID == sel.TenantID &&
+		s.Selector.Route == sel.Route &&
+		maps.Equal(s.Selector.Custom, sel.Custom)
+}
+
+// applyActionView is one step of the plan, and its result once applied.
+type applyActionView struct {
+	Action    string       `json:"action" yaml:"action"`
+	SessionID string       `json:"session_id,omitempty" yaml:"session_id,omitempty"`
+	Selector  selectorView `json:"selector" yaml:"selector"`
+	Level     string       `json:"level" yaml:"level"`
+	ExpiresAt time.Time    `json:"expires_at" yaml:"expires_at"`
+	Source    string       `json:"source" yaml:"source"`
+
+	manifest sessionManifest
+	extendBy time.Duration
+}
+
+var applyActionColumns = []column[applyActionView]{
+	{Header: "ACTION", Value: func(a applyActionView) string { return a.Action }, Color: func(a applyActionView) string {
+		switch a.Action {
+		case applyCreate:
+			return colorGreen
+		case applyExtend:
+			return colorYellow
+		}
+		return ""
+	}},
+	{Header: "SESSION", Value: func(a applyActionView) string { return orDash(a.SessionID) }},
+	{Header: "SELECTOR", Value: func(a applyActionView) string { return formatSelector(trek.Selector(a.Selector)) }, Truncate: 30},
+	{Header: "LEVEL", Value: func(a applyActionView) string { return a.Level }},
+	{Header: "CHANGE", Value: func(a applyActionView) string {
+		switch a.Action {
+		case applyCreate:
+			return "ttl " + a.manifest.TTL
+		case applyExtend:
+			return "+" + a.extendBy.String()
+		}
+		return "-"
+	}},
+	{Header: "SOURCE", Value: func(a applyActionView) string { return a.Source }},
+}
+
+// planApply works out what to do for each manifest given the active
+// sessions.
+func planApply(manifests []sessionManifest, active []trek.Session, now time.Time) ([]applyActionView, error) {
+	plan := make([]applyActionView, 0, len(manifests))
+	for i, m := range manifests {
+		for _, prev := range manifests[:i] {
+			if prev.matches(trek.Session{Selector: trek.Selector(m.Selector), Level: trek.Level(m.Level)}) {
+				return nil, fmt.Errorf("%s and %s describe the same session", prev.Source, m.Source)
+			}
+		}
+
+		action := applyActionView{
+			Action:    applyCreate,
+			Selector:  m.Selector,
+			Level:     m.Level,
+			ExpiresAt: now.Add(m.ttl),
+			Source:    m.Source,
+			manifest:  m,
+		}
+		for _, s := range active {
+			if !m.matches(s) {
+				continue
+			}
+			action.SessionID = s.ID
+			if want := now.Add(m.ttl); s.ExpiresAt.Before(want.Add(-applyTolerance)) {
+				action.Action = applyExtend
+				action.extendBy = want.Sub(s.ExpiresAt).Round(time.Second)
+			} else {
+				action.Action = applyUnchanged
+				action.ExpiresAt = s.ExpiresAt
+			}
+			break
+		}
+		plan = append(plan, action)
+	}
+	return plan, nil
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	if applyFile == "" {
+		return usageError("a manifest is required: trek session apply -f <file>")
+	}
+	var data []byte
+	var err error
+	if applyFile == "-" {
+		data, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		data, err = os.ReadFile(applyFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+	name := applyFile
+	if name == "-" {
+		name = "stdin"
+	}
+	manifests, err := readManifests(name, data)
+	if err != nil {
+		return &cliError{Code: codeUsage, ExitCode: exitUsage, Err: err}
+	}
+
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(cmd.Context())
+	active, err := client.ListSessions(ctx, "active")
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	plan, err := planApply(manifests, active, time.Now())
+	if err != nil {
+		return &cliError{Code: codeUsage, ExitCode: exitUsage, Err: err}
+	}
+
+	human := humanOutput()
+	if human {
+		printPlanSummary(plan)
+		if err := printList(plan, applyActionColumns, ""); err != nil {
+			return err
+		}
+	}
+	if applyDryRun {
+		if human {
+			return nil
+		}
+		return printList(plan, applyActionColumns, "")
+	}
+
+	pending := 0
+	for _, a := range plan {
+		if a.Action != applyUnchanged {
+			pending++
+		}
+	}
+	if pending == 0 {
+		if human {
+			fmt.Fprintln(stdout, "\nNothing to do.")
+			return nil
+		}
+		return printList(plan, applyActionColumns, "")
+	}
+
+	if !applyYes {
+		if !human || applyFile == "-" {
+			return usageError("--yes is required to apply without a prompt (with -f - or a machine-readable --output)")
+		}
+		fmt.Fprint(stdout, "\nApply these changes? [y/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" && response != "yes" {
+			fmt.Fprintln(stdout, "Cancelled.")
+			return nil
+		}
+	}
+
+	for i := range plan {
+		a := &plan[i]
+		if a.Action == applyUnchanged {
+			continue
+		}
+		if err := applyAction(cmd, client, a); err != nil {
+			return err
+		}
+		if human {
+			done := map[string]string{applyCreate: "Created", applyExtend: "Extended"}[a.Action]
+			fmt.Fprintf(stdout, "%s %s (%s), expires %s\n", done, a.SessionID, a.Source, a.ExpiresAt.Format(time.RFC3339))
+		}
+	}
+	if human {
+		return nil
+	}
+	return printList(plan, applyActionColumns, "")
+}
+
+// applyAction carries out one create or extend and records the result in a.
+func applyAction(cmd *cobra.Command, client *trek.Client, a *applyActionView) error {
+	ctx, cancel := withTimeout(cmd.Context())
+	defer cancel()
+
+	if a.Action == applyCreate {
+		resp, err := client.CreateSession(ctx, a.manifest.createRequest())
+		if err != nil {
+			return fmt.Errorf("failed to create session for %s: %w", a.Source, err)
+		}
+		a.SessionID, a.ExpiresAt = resp.ID, resp.ExpiresAt
+		return nil
+	}
+
+	resp, err := client.ExtendSession(ctx, a.SessionID, int(a.extendBy.Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to extend session %s for %s: %w", a.SessionID, a.Source, err)
+	}
+	a.ExpiresAt = resp.ExpiresAt
+	return nil
+}
+
+func (m sessionManifest) createRequest() trek.CreateSessionRequest {
+	req := trek.CreateSessionRequest{
+		Selector:   trek.Selector(m.Selector),
+		Level:      trek.Level(m.Level),
+		TTLSeconds: int(m.ttl.Seconds()),
+		Reason:     m.Reason,
+		Labels:     m.Labels,
+	}
+	if m.Caps != nil {
+		caps := trek.Caps(*m.Caps)
+		req.Caps = &caps
+	}
+	return req
+}
+
+func printPlanSummary(plan []applyActionView) {
+	counts := map[string]int{}
+	for _, a := range plan {
+		counts[a.Action]++
+	}
+	fmt.Fprintf(stdout, "Plan: %d to create, %d to extend, %d unchanged\n\n",
+		counts[applyCreate], counts[applyExtend], counts[applyUnchanged])
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+)
+
+func TestReadManifests(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantCount int
+		wantErr   string
+	}{
+		{
+			name:      "multiple documents",
+			data:      "selector:\n  user_id: u1\nttl: 10m\n---\nselector:\n  route: /api/*\n  custom:\n    region: eu\nlevel: trace\nttl: 1h\nreason: slow\nlabels:\n  ticket: T-1\n",
+			wantCount: 2,
+		},
+		{
+			name:      "json",
+			data:      `{"selector": {"tenant_id": "t1"}, "ttl": "5m", "caps": {"max_debug_events_per_request": 10}}`,
+			wantCount: 1,
+		},
+		{name: "empty", data: "", wantErr: "has no session manifests"},
+		{name: "unknown field", data: "selector:\n  user_id: u1\nttl: 10m\nttl_seconds: 60\n", wantErr: "failed to parse test.yaml#1"},
+		{name: "empty selector", data: "ttl: 10m\n", wantErr: "selector needs at least one"},
+		{name: "missing ttl", data: "selector:\n  user_id: u1\n", wantErr: "ttl is required"},
+		{name: "bad ttl", data: "selector:\n  user_id: u1\nttl: soon\n", wantErr: `invalid ttl "soon"`},
+		{name: "negative caps", data: "selector:\n  user_id: u1\nttl: 10m\ncaps:\n  max_debug_events_per_request: -1\n", wantErr: "caps must not be negative"},
+		{name: "bad level", data: "selector:\n  user_id: u1\nttl: 10m\nlevel: info\n", wantErr: "level must be debug or trace"},
+		{name: "second document invalid", data: "selector:\n  user_id: u1\nttl: 10m\n---\nttl: 10m\n", wantErr: "invalid manifest test.yaml#2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readManifests("test.yaml", []byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readManifests() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readManifests() error = %v", err)
+			}
+			if len(got) != tt.wantCount {
+				t.Fatalf("got %d manifests, want %d", len(got), tt.wantCount)
+			}
+			if got[0].Level != "debug" || got[0].Source != "test.yaml#1" {
+				t.Errorf("first manifest = %+v, want default level and source test.yaml#1", got[0])
+			}
+		})
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	manifest := func(user, ttl string) sessionManifest {
+		m := sessionManifest{Selector: selectorView{UserID: user}, TTL: ttl, Source: "m.yaml#1"}
+		if err := m.validate(); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	active := func(id, user string, expiresIn time.Duration) trek.Session {
+		return trek.Session{ID: id, Selector: trek.Selector{UserID: user}, Level: trek.LevelDebug, ExpiresAt: now.Add(expiresIn)}
+	}
+
+	tests := []struct {
+		name       string
+		manifest   sessionManifest
+		active     []trek.Session
+		wantAction string
+		wantID     string
+		wantExtend time.Duration
+	}{
+		{name: "no session", manifest: manifest("u1", "30m"), wantAction: applyCreate},
+		{name: "other user", manifest: manifest("u1", "30m"), active: []trek.Session{active("s1", "u2", time.Hour)}, wantAction: applyCreate},
+		{name: "expires too soon", manifest: manifest("u1", "30m"), active: []trek.Session{active("s1", "u1", 10*time.Minute)}, wantAction: applyExtend, wantID: "s1", wantExtend: 20 * time.Minute},
+		{name: "within tolerance", manifest: manifest("u1", "30m"), active: []trek.Session{active("s1", "u1", 29*time.Minute+30*time.Second)}, wantAction: applyUnchanged, wantID: "s1"},
+		{name: "outlives manifest", manifest: manifest("u1", "30m"), active: []trek.Session{active("s1", "u1", time.Hour)}, wantAction: applyUnchanged, wantID: "s1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planApply([]sessionManifest{tt.manifest}, tt.active, now)
+			if err != nil {
+				t.Fatalf("planApply() error = %v", err)
+			}
+			got := plan[0]
+			if got.Action != tt.wantAction || got.SessionID != tt.wantID || got.extendBy != tt.wantExtend {
+				t.Errorf("plan = %s %s +%s, want %s %s +%s", got.Action, got.SessionID, got.extendBy, tt.wantAction, tt.wantID, tt.wantExtend)
+			}
+		})
+	}
+
+	_, err := planApply([]sessionManifest{manifest("u1", "10m"), manifest("u1", "20m")}, nil, now)
+	if err == nil || !contains(err.Error(), "describe the same session") {
+		t.Errorf("duplicate manifests error = %v, want a duplicate error", err)
+	}
+}
+
+// setApplyFlags sets the flags of 'trek session apply' for one test.
+func setApplyFlags(t *testing.T, file string, yes, dryRun bool) {
+	t.Helper()
+	oldFile, oldYes, oldDryRun := applyFile, applyYes, applyDryRun
+	t.Cleanup(func() { applyFile, applyYes, applyDryRun = oldFile, oldYes, oldDryRun })
+	applyFile, applyYes, applyDryRun = file, yes, dryRun
+}
+
+func TestApplyAgainstDevServer(t *testing.T) {
+	srv := startDevServer(t, trek.Policy{MaxTTLSeconds: 3600})
+	buf := captureOutput(t, outputJSON)
+
+	file := filepath.Join(t.TempDir(), "debug.yaml")
+	manifests := "selector:\n  user_id: u1\nttl: 10m\nreason: checkout\ncaps:\n  max_debug_events_per_request: 25\n---\nselector:\n  route: /api/orders*\nlevel: trace\nttl: 5m\n"
+	if err := os.WriteFile(file, []byte(manifests), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	setApplyFlags(t, file, false, true)
+	if err := runApply(sessionApplyCmd, nil); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	var plan listView[applyActionView]
+	decodeOutput(t, buf, &plan)
+	if len(plan.Items) != 2 || plan.Items[0].Action != applyCreate || len(srv.sessions) != 0 {
+		t.Fatalf("dry run plan = %+v with %d sessions, want two creates and no sessions", plan.Items, len(srv.sessions))
+	}
+
+	applyDryRun = false
+	if err := runApply(sessionApplyCmd, nil); err == nil || !contains(err.Error(), "--yes is required") {
+		t.Fatalf("apply without --yes in JSON mode = %v, want a --yes error", err)
+	}
+
+	applyYes = true
+	if err := runApply(sessionApplyCmd, nil); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	decodeOutput(t, buf, &plan)
+	if len(plan.Items) != 2 || plan.Items[0].SessionID == "" || plan.Items[1].SessionID == "" {
+		t.Fatalf("applied = %+v, want two created sessions", plan.Items)
+	}
+	first := srv.sessions[0].Session
+	if first.Reason != "checkout" || first.Caps.MaxDebugEventsPerRequest != 25 {
+		t.Errorf("created session = %+v, want the manifest's reason and caps", first)
+	}
+
+	if err := runApply(sessionApplyCmd, nil); err != nil {
+		t.Fatalf("re-apply: %v", err)
+	}
+	decodeOutput(t, buf, &plan)
+	for _, a := range plan.Items {
+		if a.Action != applyUnchanged {
+			t.Errorf("re-apply action for %s = %s, want unchanged", a.Source, a.Action)
+		}
+	}
+
+	srv.sessions[0].ExpiresAt = time.Now().Add(2 * time.Minute)
+	if err := runApply(sessionApplyCmd, nil); err != nil {
+		t.Fatalf("apply after time passed: %v", err)
+	}
+	decodeOutput(t, buf, &plan)
+	if plan.Items[0].Action != applyExtend || !plan.Items[0].ExpiresAt.After(time.Now().Add(9*time.Minute)) {
+		t.Errorf("apply after time passed = %+v, want the first session extended to about 10m", plan.Items[0])
+	}
+}
+
+func TestApplyFromStdinRequiresYes(t *testing.T) {
+	startDevServer(t, trek.Policy{})
+	captureOutput(t, outputTable)
+	setApplyFlags(t, "-", false, false)
+
+	sessionApplyCmd.SetIn(strings.NewReader("selector:\n  user_id: u1\nttl: 10m\n"))
+	defer sessionApplyCmd.SetIn(nil)
+
+	err := runApply(sessionApplyCmd, nil)
+	if got := classifyError(err); got.ExitCode != exitUsage || !contains(err.Error(), "--yes is required") {
+		t.Errorf("apply from stdin without --yes = %v, want a usage error", err)
+	}
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"time"
 
@@ -43,7 +42,7 @@ func runAuditList(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := withTimeout(cmd.Context())
 	defer cancel()
 
 	var sinceTime time.Time
@@ -60,72 +59,38 @@ func runAuditList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to list audit events: %w", err)
 	}
 
-	events := resp.Events
-	if !sinceTime.IsZero() {
-		var filtered []struct {
-			ID          string
-			Action      string
-			TargetType  string
-			TargetID    string
-			ActorUserID string
-			CreatedAt   time.Time
-		}
-		for _, e := range events {
-			if e.CreatedAt.After(sinceTime) {
-				filtered = append(filtered, struct {
-					ID          string
-					Action      string
-					TargetType  string
-					TargetID    string
-					ActorUserID string
-					CreatedAt   time.Time
-				}{
-					ID:          e.ID,
-					Action:      e.Action,
-					TargetType:  e.TargetType,
-					TargetID:    e.TargetID,
-					ActorUserID: e.ActorUserID,
-					CreatedAt:   e.CreatedAt,
-				})
-			}
-		}
-		if len(filtered) == 0 {
-			fmt.Printf("No audit events found since %s\n", sinceTime.Format(time.RFC3339))
-			return nil
+	var views []auditEventView
+	for _, e := range resp.Events {
+		if !sinceTime.IsZero() && !e.CreatedAt.After(sinceTime) {
+			continue
 		}
-		fmt.Printf("Audit events since %s\n", sinceTime.Format("15:04:05"))
-		fmt.Println("------------------------------------------")
-		fmt.Printf("%-20s %-20s %-15s %-28s %s\n", "TIME", "ACTION", "TARGET TYPE", "TARGET ID", "ACTOR")
-		fmt.Println("------------------------------------------------------------------------------------------------------")
-		for _, e := range filtered {
-			fmt.Printf("%-20s %-20s %-15s %-28s %s\n",
-				e.CreatedAt.Format("2006-01-02 15:04:05"),
-				e.Action,
-				e.TargetType,
-				truncate(e.TargetID, 28),
-				e.ActorUserID,
-			)
-		}
-		return nil
+		views = append(views, auditEventView(e))
 	}
 
-	if len(events) == 0 {
-		fmt.Println("No audit events found")
-		return nil
+	empty := "No audit events found"
+	if !sinceTime.IsZero() {
+		empty = fmt.Sprintf("No audit events found since %s", sinceTime.Format(time.RFC3339))
 	}
+	return printList(views, auditEventColumns, empty)
+}
 
-	fmt.Printf("%-20s %-20s %-15s %-28s %s\n", "TIME", "ACTION", "TARGET TYPE", "TARGET ID", "ACTOR")
-	fmt.Println("------------------------------------------------------------------------------------------------------")
-
-	for _, e := range events {
-		fmt.Printf("%-20s %-20s %-15s %-28s %s\n",
-			e.CreatedAt.Format("2006-01-02 15:04:05"),
-			e.Action,
-			e.TargetType,
-			truncate(e.TargetID, 28),
-			e.ActorUserID,
-		)
-	}
+type auditEventView struct {
+	ID          string    `json:"id" yaml:"id"`
+	Action      string    `json:"action" yaml:"action"`
+	TargetType  string    `json:"target_type" yaml:"target_type"`
+	TargetID    string    `json:"target_id" yaml:"target_id"`
+	ActorUserID string    `json:"actor_user_id" yaml:"actor_user_id"`
+	CreatedAt   time.Time `json:"created_at" yaml:"created_at"`
+}
+
+func (v auditEventView) id() string { return v.ID }
 
-	return nil
+var auditEventColumns = []column[auditEventView]{
+	{Header: "TIME", Value: func(e auditEventView) string { return e.CreatedAt.Format("2006-01-02 15:04:05") }},
+	{Header: "ACTION", Value: func(e auditEventView) string { return e.Action },
+		Color: func(e auditEventView) string { return auditActionColor(e.Action) }},
+	{Header: "TARGET TYPE", Value: func(e auditEventView) string { return e.TargetType }},
+	{Header: "TARGET ID", Value: func(e auditEventView) string { return e.TargetID }, Truncate: 28},
+	{Header: "ACTOR", Value: func(e auditEventView) string { return e.ActorUserID }},
+	{Header: "ID", Value: func(e auditEventView) string { return e.ID }, Wide: true},
 }
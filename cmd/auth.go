@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -22,22 +23,48 @@ var authCmd = &cobra.Command{
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
-	Short: "Authenticate with Trek using Clerk",
-	Long: `Authenticate with Trek using Clerk's device authorization flow.
-This will open a browser for you to sign in, then store the token locally.`,
+	Short: "Authenticate with Trek",
+	Long: `Authenticate with Trek using Clerk or any OIDC provider.
+
+Clerk is used with --clerk-domain. For other providers (Okta, Keycloak, Dex,
+...) pass --issuer and the endpoints are found via OIDC discovery.
+
+By default this uses the device authorization flow: open the printed URL on
+any device and enter the code. With --method browser, a browser is opened on
+this machine and the login completes automatically via a loopback redirect;
+on machines without a browser it falls back to the device flow.
+
+Examples:
+  trek auth login --clerk-domain clerk.example.com --client-id abc123
+  trek auth login --method browser
+  trek auth login --issuer https://keycloak.example.com/realms/trek --client-id trek-cli`,
 	RunE: runLogin,
 }
 
 var logoutCmd = &cobra.Command{
 	Use:   "logout",
 	Short: "Log out and remove stored credentials",
-	RunE:  runLogout,
+	Long: `Revoke the stored access and refresh tokens at the identity provider,
+then remove them from this machine. If the provider can't be reached, the
+local credentials are still removed and a warning is printed.
+
+Examples:
+  trek auth logout
+  trek auth logout --profile staging
+  trek auth logout --all-profiles`,
+	RunE: runLogout,
 }
 
 var whoamiCmd = &cobra.Command{
 	Use:   "whoami",
 	Short: "Display current authentication status",
-	RunE:  runWhoami,
+	Long: `Display who you are logged in as, with the token's scopes and remaining
+lifetime.
+
+Examples:
+  trek auth whoami
+  trek auth whoami -o json`,
+	RunE: runWhoami,
 }
 
 func init() {
@@ -47,10 +74,14 @@ func init() {
 	authCmd.AddCommand(whoamiCmd)
 
 	loginCmd.Flags().String("clerk-domain", "", "Clerk domain (e.g., clerk.example.com)")
-	loginCmd.Flags().String("client-id", "", "Clerk OAuth client ID")
+	loginCmd.Flags().String("client-id", "", "OAuth client ID")
+	loginCmd.Flags().String("issuer", "", "OIDC issuer URL (uses discovery instead of the Clerk preset)")
+	loginCmd.Flags().String("method", "device", "Login method: device or browser")
+
+	logoutCmd.Flags().Bool("all-profiles", false, "Log out of every stored login, not just the active profile")
 }
 
-// DeviceAuthResponse is the response from Clerk's device authorization endpoint.
+// DeviceAuthResponse is the response from the provider's device authorization endpoint.
 type DeviceAuthResponse struct {
 	DeviceCode              string `json:"device_code"`
 	UserCode                string `json:"user_code"`
@@ -60,13 +91,14 @@ type DeviceAuthResponse struct {
 	Interval                int    `json:"interval"`
 }
 
-// TokenResponse is the response from Clerk's token endpoint.
+// TokenResponse is the response from the provider's token endpoint.
 type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int    `json:"expires_in"`
 	RefreshToken string `json:"refresh_token,omitempty"`
 	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
 	Error        string `json:"error,omitempty"`
 	ErrorDesc    string `json:"error_description,omitempty"`
 }
@@ -77,13 +109,129 @@ type StoredCredentials struct {
 	RefreshToken string    `json:"refresh_token,omitempty"`
 	ExpiresAt    time.Time `json:"expires_at"`
 	Email        string    `json:"email,omitempty"`
+
+	// Identity taken from the validated ID token at login.
+	Subject string   `json:"subject,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Org     string   `json:"org,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+
+	// Issuer, ClientID and TokenEndpoint are kept so the access token can
+	// be refreshed without the login flags.
+	Issuer        string `json:"issuer,omitempty"`
+	ClientID      string `json:"client_id,omitempty"`
+	TokenEndpoint string `json:"token_endpoint,omitempty"`
+
+	// RevocationEndpoint is where logout revokes the tokens (RFC 7009).
+	RevocationEndpoint string `json:"revocation_endpoint,omitempty"`
 }
 
 func runLogin(cmd *cobra.Command, args []string) error {
+	method, _ := cmd.Flags().GetString("method")
+	if method != "device" && method != "browser" {
+		return usageError("invalid --method %q: expected device or browser", method)
+	}
+
+	// Login waits for the user, so only Ctrl+C ends it; --timeout bounds
+	// each request.
+	ctx := cmd.Context()
+
+	discoverCtx, cancel := withTimeout(ctx)
+	provider, clientID, err := resolveLoginProvider(discoverCtx, cmd)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	var token *TokenResponse
+	switch method {
+	case "browser":
+		if isHeadless() {
+			fmt.Println("No browser available on this machine, using device authorization instead.")
+			token, err = deviceLogin(ctx, provider, clientID)
+			break
+		}
+		if provider.AuthorizationEndpoint == "" {
+			return fmt.Errorf("provider %s does not support browser login, use --method device", provider.Issuer)
+		}
+		token, err = browserLogin(ctx, provider.AuthorizationEndpoint, provider.TokenEndpoint, clientID)
+		if errors.Is(err, errBrowserUnavailable) {
+			fmt.Printf("%v, using device authorization instead.\n", err)
+			token, err = deviceLogin(ctx, provider, clientID)
+		}
+	default:
+		token, err = deviceLogin(ctx, provider, clientID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Save credentials
+	creds := StoredCredentials{
+		AccessToken:   token.AccessToken,
+		RefreshToken:  token.RefreshToken,
+		ExpiresAt:     time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+		Scopes:        strings.Fields(token.Scope),
+		Issuer:        provider.Issuer,
+		ClientID:      clientID,
+		TokenEndpoint: provider.TokenEndpoint,
+
+		RevocationEndpoint: provider.RevocationEndpoint,
+	}
+
+	if token.IDToken != "" {
+		validateCtx, cancel := withTimeout(ctx)
+		claims, err := validateIDToken(validateCtx, provider, clientID, token.IDToken)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("invalid ID token: %w", err)
+		}
+		creds.Subject = claims.Subject
+		creds.Email = claims.Email
+		creds.Name = claims.Name
+		creds.Org = claims.organization()
+	}
+
+	if err := saveCredentials(creds); err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+
+	if creds.Email != "" {
+		fmt.Printf("\n✅ Successfully authenticated as %s!\n", creds.Email)
+		return nil
+	}
+	fmt.Println("\n✅ Successfully authenticated!")
+	return nil
+}
+
+// resolveLoginProvider works out which identity provider to log in against.
+// An OIDC issuer (--issuer or TREK_OIDC_ISSUER) is used via discovery;
+// otherwise the Clerk preset is built from the Clerk domain.
+func resolveLoginProvider(ctx context.Context, cmd *cobra.Command) (*oidcProvider, string, error) {
+	issuer, _ := cmd.Flags().GetString("issuer")
 	clerkDomain, _ := cmd.Flags().GetString("clerk-domain")
 	clientID, _ := cmd.Flags().GetString("client-id")
 
 	// Try to get from environment if not provided
+	if issuer == "" && clerkDomain == "" {
+		issuer = os.Getenv("TREK_OIDC_ISSUER")
+	}
+
+	if issuer != "" {
+		if clientID == "" {
+			clientID = os.Getenv("TREK_OIDC_CLIENT_ID")
+		}
+		if clientID == "" {
+			return nil, "", fmt.Errorf("client-id is required with --issuer (set via flag or TREK_OIDC_CLIENT_ID env var)")
+		}
+
+		provider, err := discoverProvider(ctx, issuer)
+		if err != nil {
+			return nil, "", fmt.Errorf("OIDC discovery failed: %w", err)
+		}
+		return provider, clientID, nil
+	}
+
 	if clerkDomain == "" {
 		clerkDomain = os.Getenv("TREK_CLERK_DOMAIN")
 	}
@@ -92,78 +240,177 @@ func runLogin(cmd *cobra.Command, args []string) error {
 	}
 
 	if clerkDomain == "" || clientID == "" {
-		return fmt.Errorf("clerk-domain and client-id are required (set via flags or TREK_CLERK_DOMAIN/TREK_CLERK_CLIENT_ID env vars)")
+		return nil, "", fmt.Errorf("clerk-domain and client-id are required (set via flags or TREK_CLERK_DOMAIN/TREK_CLERK_CLIENT_ID env vars), or use --issuer for another OIDC provider")
 	}
 
-	ctx := context.Background()
+	return clerkProvider(clerkDomain), clientID, nil
+}
+
+// deviceLogin runs the device authorization flow: the user opens a URL on any
+// device and enters a code while we poll the token endpoint.
+func deviceLogin(ctx context.Context, provider *oidcProvider, clientID string) (*TokenResponse, error) {
+	if provider.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("provider %s does not support device authorization, use --method browser", provider.Issuer)
+	}
 
 	// Step 1: Request device authorization
-	deviceAuth, err := requestDeviceAuthorization(ctx, clerkDomain, clientID)
+	authCtx, cancel := withTimeout(ctx)
+	deviceAuth, err := requestDeviceAuthorization(authCtx, provider.DeviceAuthorizationEndpoint, clientID)
+	cancel()
 	if err != nil {
-		return fmt.Errorf("device authorization failed: %w", err)
+		return nil, fmt.Errorf("device authorization failed: %w", err)
 	}
 
 	// Step 2: Display instructions to user
 	fmt.Println("\n🔐 Trek Authentication")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Printf("\nOpen this URL in your browser:\n\n  %s\n\n", deviceAuth.VerificationURIComplete)
+	verificationURI := deviceAuth.VerificationURIComplete
+	if verificationURI == "" {
+		verificationURI = deviceAuth.VerificationURI
+	}
+	fmt.Printf("\nOpen this URL in your browser:\n\n  %s\n\n", verificationURI)
 	fmt.Printf("Or go to %s and enter code: %s\n\n", deviceAuth.VerificationURI, deviceAuth.UserCode)
 	fmt.Println("Waiting for authentication...")
 
 	// Step 3: Poll for token
-	token, err := pollForToken(ctx, clerkDomain, clientID, deviceAuth)
+	token, err := pollForToken(ctx, provider.TokenEndpoint, clientID, deviceAuth)
 	if err != nil {
-		return fmt.Errorf("authentication failed: %w", err)
+		return nil, fmt.Errorf("authentication failed: %w", err)
 	}
 
-	// Step 4: Save credentials
-	creds := StoredCredentials{
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		ExpiresAt:    time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+	return token, nil
+}
+
+func runLogout(cmd *cobra.Command, args []string) error {
+	ctx, cancel := withTimeout(cmd.Context())
+	defer cancel()
+
+	store, err := currentCredentialStore()
+	if err != nil {
+		return err
 	}
 
-	if err := saveCredentials(creds); err != nil {
-		return fmt.Errorf("failed to save credentials: %w", err)
+	keys := []string{credentialKey()}
+	if allProfiles, _ := cmd.Flags().GetBool("all-profiles"); allProfiles {
+		keys, err = store.List()
+		if err != nil {
+			return fmt.Errorf("failed to list stored credentials: %w", err)
+		}
 	}
 
-	fmt.Println("\n✅ Successfully authenticated!")
-	return nil
-}
+	var failures []string
+	for _, key := range keys {
+		if len(keys) > 1 {
+			fmt.Printf("%s:\n", key)
+		}
 
-func runLogout(cmd *cobra.Command, args []string) error {
-	credPath := getCredentialsPath()
-	if err := os.Remove(credPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove credentials: %w", err)
+		var failed []string
+		if creds, err := store.Load(key); err == nil {
+			failed = revokeCredentials(ctx, creds)
+		}
+		if err := store.Delete(key); err != nil {
+			return fmt.Errorf("failed to remove credentials for %s: %w", key, err)
+		}
+
+		if len(failed) > 0 {
+			failures = append(failures, fmt.Sprintf("%s (%s)", key, strings.Join(failed, " and ")))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("removed local credentials, but the provider did not revoke: %s", strings.Join(failures, ", "))
 	}
 
 	fmt.Println("✅ Logged out successfully")
 	return nil
 }
 
+// whoamiStatus is the machine-readable form of 'trek auth whoami'.
+type whoamiStatus struct {
+	Authenticated    bool       `json:"authenticated" yaml:"authenticated"`
+	Profile          string     `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Reason           string     `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Subject          string     `json:"subject,omitempty" yaml:"subject,omitempty"`
+	Email            string     `json:"email,omitempty" yaml:"email,omitempty"`
+	Name             string     `json:"name,omitempty" yaml:"name,omitempty"`
+	Org              string     `json:"org,omitempty" yaml:"org,omitempty"`
+	Issuer           string     `json:"issuer,omitempty" yaml:"issuer,omitempty"`
+	Scopes           []string   `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	ExpiresInSeconds int        `json:"expires_in_seconds,omitempty" yaml:"expires_in_seconds,omitempty"`
+}
+
 func runWhoami(cmd *cobra.Command, args []string) error {
-	creds, err := loadCredentials()
-	if err != nil {
-		fmt.Println("Not authenticated. Run 'trek auth login' to authenticate.")
-		return nil
+	ctx, cancel := withTimeout(cmd.Context())
+	defer cancel()
+
+	var status whoamiStatus
+	creds, err := ensureFreshCredentials(ctx)
+	switch {
+	case err == nil:
+		status = whoamiStatus{
+			Authenticated:    true,
+			Subject:          creds.Subject,
+			Email:            creds.Email,
+			Name:             creds.Name,
+			Org:              creds.Org,
+			Issuer:           creds.Issuer,
+			Scopes:           creds.Scopes,
+			ExpiresAt:        &creds.ExpiresAt,
+			ExpiresInSeconds: int(time.Until(creds.ExpiresAt).Seconds()),
+		}
+	case errors.Is(err, os.ErrNotExist):
+		status = whoamiStatus{Reason: "not_logged_in"}
+	case errors.Is(err, errCredentialsExpired) || errors.Is(err, errRefreshRevoked):
+		status = whoamiStatus{Reason: "expired"}
+	default:
+		return err
 	}
 
-	if time.Now().After(creds.ExpiresAt) {
-		fmt.Println("Session expired. Run 'trek auth login' to re-authenticate.")
-		return nil
+	if activeContext != nil {
+		status.Profile = activeContext.Name
 	}
 
-	fmt.Printf("Authenticated\n")
-	if creds.Email != "" {
-		fmt.Printf("Email: %s\n", creds.Email)
-	}
-	fmt.Printf("Expires: %s\n", creds.ExpiresAt.Format(time.RFC3339))
-	return nil
+	return printItem(status, printWhoami)
 }
 
-func requestDeviceAuthorization(ctx context.Context, clerkDomain, clientID string) (*DeviceAuthResponse, error) {
-	endpoint := fmt.Sprintf("https://%s/oauth/device/code", clerkDomain)
+func printWhoami(w io.Writer, s whoamiStatus) {
+	switch s.Reason {
+	case "not_logged_in":
+		fmt.Fprintln(w, "Not authenticated. Run 'trek auth login' to authenticate.")
+		return
+	case "expired":
+		fmt.Fprintln(w, "Session expired. Run 'trek auth login' to re-authenticate.")
+		return
+	}
 
+	fmt.Fprintf(w, "Authenticated\n")
+	if s.Profile != "" {
+		fmt.Fprintf(w, "Profile: %s\n", s.Profile)
+	}
+	if s.Subject != "" {
+		fmt.Fprintf(w, "Subject: %s\n", s.Subject)
+	}
+	if s.Email != "" {
+		fmt.Fprintf(w, "Email: %s\n", s.Email)
+	}
+	if s.Name != "" {
+		fmt.Fprintf(w, "Name: %s\n", s.Name)
+	}
+	if s.Org != "" {
+		fmt.Fprintf(w, "Org: %s\n", s.Org)
+	}
+	if s.Issuer != "" {
+		fmt.Fprintf(w, "Issuer: %s\n", s.Issuer)
+	}
+	if len(s.Scopes) > 0 {
+		fmt.Fprintf(w, "Scopes: %s\n", strings.Join(s.Scopes, " "))
+	}
+	remaining := (time.Duration(s.ExpiresInSeconds) * time.Second).String()
+	fmt.Fprintf(w, "Expires: %s (in %s)\n", s.ExpiresAt.Format(time.RFC3339), remaining)
+}
+
+func requestDeviceAuthorization(ctx context.Context, endpoint, clientID string) (*DeviceAuthResponse, error) {
 	data := url.Values{}
 	data.Set("client_id", clientID)
 	data.Set("scope", "openid email profile")
@@ -193,9 +440,7 @@ func requestDeviceAuthorization(ctx context.Context, clerkDomain, clientID strin
 	return &result, nil
 }
 
-func pollForToken(ctx context.Context, clerkDomain, clientID string, deviceAuth *DeviceAuthResponse) (*TokenResponse, error) {
-	endpoint := fmt.Sprintf("https://%s/oauth/token", clerkDomain)
-
+func pollForToken(ctx context.Context, endpoint, clientID string, deviceAuth *DeviceAuthResponse) (*TokenResponse, error) {
 	interval := time.Duration(deviceAuth.Interval) * time.Second
 	if interval < 5*time.Second {
 		interval = 5 * time.Second
@@ -215,20 +460,24 @@ func pollForToken(ctx context.Context, clerkDomain, clientID string, deviceAuth
 		data.Set("device_code", deviceAuth.DeviceCode)
 		data.Set("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
 
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(data.Encode()))
+		reqCtx, cancel := withTimeout(ctx)
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, endpoint, strings.NewReader(data.Encode()))
 		if err != nil {
+			cancel()
 			return nil, err
 		}
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
+			cancel()
 			continue
 		}
 
 		var result TokenResponse
 		json.NewDecoder(resp.Body).Decode(&result)
 		resp.Body.Close()
+		cancel()
 
 		switch result.Error {
 		case "":
@@ -251,50 +500,81 @@ func pollForToken(ctx context.Context, clerkDomain, clientID string, deviceAuth
 }
 
 func getCredentialsPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".trek", "credentials.json")
+	return filepath.Join(trekDir(), credentialFileName(defaultCredentialKey, ".json"))
 }
 
-func saveCredentials(creds StoredCredentials) error {
-	path := getCredentialsPath()
+// credentialKey names the entry in the credential store used for login:
+// the active context's credential, or the default entry without contexts.
+func credentialKey() string {
+	if activeContext != nil {
+		return activeContext.credentialKey()
+	}
+	if profileName != "" {
+		return profileName
+	}
+	return defaultCredentialKey
+}
 
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0700); err != nil {
+func saveCredentials(creds StoredCredentials) error {
+	store, err := currentCredentialStore()
+	if err != nil {
 		return err
 	}
+	return store.Save(credentialKey(), creds)
+}
 
-	data, err := json.MarshalIndent(creds, "", "  ")
+// writeFileAtomic writes data to a temp file in the same directory and
+// renames it over path, so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
 
-	return os.WriteFile(path, data, 0600)
+	return os.Rename(tmp.Name(), path)
 }
 
 func loadCredentials() (*StoredCredentials, error) {
-	path := getCredentialsPath()
-
-	data, err := os.ReadFile(path)
+	store, err := currentCredentialStore()
 	if err != nil {
 		return nil, err
 	}
+	return store.Load(credentialKey())
+}
 
-	var creds StoredCredentials
-	if err := json.Unmarshal(data, &creds); err != nil {
-		return nil, err
+func deleteCredentials() error {
+	store, err := currentCredentialStore()
+	if err != nil {
+		return err
 	}
-
-	return &creds, nil
+	return store.Delete(credentialKey())
 }
 
 // GetAccessToken returns the current access token if valid, or empty string if not authenticated.
+// Tokens close to expiry are refreshed first.
 func GetAccessToken() string {
-	creds, err := loadCredentials()
-	if err != nil {
-		return ""
-	}
+	ctx, cancel := withTimeout(rootContext())
+	defer cancel()
 
-	if time.Now().After(creds.ExpiresAt) {
+	creds, err := ensureFreshCredentials(ctx)
+	if err != nil {
 		return ""
 	}
 
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// browserLoginTimeout bounds how long we wait for the redirect back from
+// the browser.
+const browserLoginTimeout = 5 * time.Minute
+
+var errBrowserUnavailable = errors.New("could not open a browser")
+
+// openBrowser opens url in the user's default browser. It is a variable so
+// tests can stand in for the browser.
+var openBrowser = func(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// isHeadless reports whether this machine has no usable browser, such as
+// an SSH session or a Linux box without a display.
+func isHeadless() bool {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return true
+	}
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		return false
+	}
+	return os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == ""
+}
+
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// browserLogin runs the authorization-code flow with PKCE. A listener on a
+// random loopback port receives the redirect, and the state parameter is
+// checked before the code is exchanged for tokens.
+func browserLogin(ctx context.Context, authorizeEndpoint, tokenEndpoint, clientID string) (*TokenResponse, error) {
+	verifier, err := randomURLSafe(32)
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomURLSafe(16)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start loopback listener: %w", err)
+	}
+	redirectURI := fmt.Sprintf("http://127.0.0.1:%d/callback", listener.Addr().(*net.TCPAddr).Port)
+
+	results := make(chan callbackResult, 1)
+	srv := &http.Server{Handler: callbackHandler(state, results)}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	authURL := buildAuthorizeURL(authorizeEndpoint, clientID, redirectURI, state, pkceChallenge(verifier))
+
+	fmt.Println("\n🔐 Trek Authentication")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("\nOpening your browser to sign in. If it does not open, visit:\n\n  %s\n\n", authURL)
+
+	if err := openBrowser(authURL); err != nil {
+		return nil, fmt.Errorf("%w: %v", errBrowserUnavailable, err)
+	}
+	fmt.Println("Waiting for authentication...")
+
+	var result callbackResult
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(browserLoginTimeout):
+		return nil, fmt.Errorf("authentication timed out")
+	}
+	if result.err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", result.err)
+	}
+
+	exchangeCtx, cancel := withTimeout(ctx)
+	defer cancel()
+	token, err := exchangeAuthorizationCode(exchangeCtx, tokenEndpoint, clientID, result.code, verifier, redirectURI)
+	if err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
+	return token, nil
+}
+
+func buildAuthorizeURL(authorizeEndpoint, clientID, redirectURI, state, challenge string) string {
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", clientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", "openid email profile")
+	q.Set("state", state)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(authorizeEndpoint, "?") {
+		sep = "&"
+	}
+	return authorizeEndpoint + sep + q.Encode()
+}
+
+// callbackHandler serves the redirect URI. Only the first valid callback is
+// delivered; anything after that is ignored.
+func callbackHandler(state string, results chan<- callbackResult) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		var result callbackResult
+		switch {
+		case q.Get("state") != state:
+			result.err = fmt.Errorf("state mismatch in redirect")
+		case q.Get("error") != "":
+			result.err = fmt.Errorf("%s: %s", q.Get("error"), q.Get("error_description"))
+		case q.Get("code") == "":
+			result.err = fmt.Errorf("redirect missing authorization code")
+		default:
+			result.code = q.Get("code")
+		}
+
+		if result.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Trek authentication failed: %v\nReturn to your terminal for details.\n", result.err)
+		} else {
+			fmt.Fprintln(w, "Trek authentication complete. You can close this window.")
+		}
+
+		select {
+		case results <- result:
+		default:
+		}
+	})
+	return mux
+}
+
+func exchangeAuthorizationCode(ctx context.Context, tokenEndpoint, clientID, code, verifier, redirectURI string) (*TokenResponse, error) {
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("client_id", clientID)
+	data.Set("code", code)
+	data.Set("code_verifier", verifier)
+	data.Set("redirect_uri", redirectURI)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result TokenResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("token request failed: %s", string(body))
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s: %s", result.Error, result.ErrorDesc)
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("token response missing access_token")
+	}
+
+	return &result, nil
+}
+
+// pkceChallenge derives the S256 code challenge for a verifier (RFC 7636).
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomURLSafe(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// fakeBrowser replaces openBrowser with a function that follows the
+// authorize URL the way a provider would: it redirects back to the loopback
+// listener with the given code and the original state. inspect sees the
+// authorize query; mutate can tamper with the callback query.
+func fakeBrowser(t *testing.T, code string, inspect, mutate func(q url.Values)) {
+	t.Helper()
+	orig := openBrowser
+	t.Cleanup(func() { openBrowser = orig })
+
+	openBrowser = func(authURL string) error {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+		q := u.Query()
+		if inspect != nil {
+			inspect(q)
+		}
+
+		callback := url.Values{}
+		callback.Set("code", code)
+		callback.Set("state", q.Get("state"))
+		if mutate != nil {
+			mutate(callback)
+		}
+
+		go func() {
+			resp, err := http.Get(q.Get("redirect_uri") + "?" + callback.Encode())
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+}
+
+func TestBrowserLogin_Success(t *testing.T) {
+	var authorizeChallenge string
+	fakeBrowser(t, "auth-code-1", func(q url.Values) {
+		authorizeChallenge = q.Get("code_challenge")
+		if q.Get("code_challenge_method") != "S256" {
+			t.Errorf("code_challenge_method = %q, want S256", q.Get("code_challenge_method"))
+		}
+		if q.Get("client_id") != "client-123" {
+			t.Errorf("client_id = %q, want client-123", q.Get("client_id"))
+		}
+	}, nil)
+
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if got := r.PostForm.Get("grant_type"); got != "authorization_code" {
+			t.Errorf("grant_type = %q, want authorization_code", got)
+		}
+		if got := r.PostForm.Get("code"); got != "auth-code-1" {
+			t.Errorf("code = %q, want auth-code-1", got)
+		}
+		if got := pkceChallenge(r.PostForm.Get("code_verifier")); got != authorizeChallenge {
+			t.Errorf("code_verifier does not match code_challenge")
+		}
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "access-1", ExpiresIn: 3600})
+	}))
+	defer tokenSrv.Close()
+
+	token, err := browserLogin(context.Background(), "https://idp.example.com/authorize", tokenSrv.URL, "client-123")
+	if err != nil {
+		t.Fatalf("browserLogin() error: %v", err)
+	}
+	if token.AccessToken != "access-1" {
+		t.Errorf("AccessToken = %q, want %q", token.AccessToken, "access-1")
+	}
+}
+
+func TestBrowserLogin_StateMismatch(t *testing.T) {
+	fakeBrowser(t, "auth-code-1", nil, func(q url.Values) {
+		q.Set("state", "forged")
+	})
+
+	_, err := browserLogin(context.Background(), "https://idp.example.com/authorize", "http://127.0.0.1:1/token", "client-123")
+	if err == nil {
+		t.Fatal("expected error for state mismatch")
+	}
+	if !contains(err.Error(), "state mismatch") {
+		t.Errorf("error = %q, want containing %q", err.Error(), "state mismatch")
+	}
+}
+
+func TestBrowserLogin_ProviderError(t *testing.T) {
+	fakeBrowser(t, "", nil, func(q url.Values) {
+		q.Del("code")
+		q.Set("error", "access_denied")
+		q.Set("error_description", "user cancelled")
+	})
+
+	_, err := browserLogin(context.Background(), "https://idp.example.com/authorize", "http://127.0.0.1:1/token", "client-123")
+	if err == nil || !contains(err.Error(), "access_denied") {
+		t.Errorf("error = %v, want access_denied", err)
+	}
+}
+
+func TestBrowserLogin_BrowserUnavailable(t *testing.T) {
+	orig := openBrowser
+	defer func() { openBrowser = orig }()
+	openBrowser = func(string) error { return errors.New("xdg-open not found") }
+
+	_, err := browserLogin(context.Background(), "https://idp.example.com/authorize", "http://127.0.0.1:1/token", "client-123")
+	if !errors.Is(err, errBrowserUnavailable) {
+		t.Errorf("error = %v, want errBrowserUnavailable", err)
+	}
+}
+
+func TestPKCEChallenge(t *testing.T) {
+	// Test vector from RFC 7636 appendix B.
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if got := pkceChallenge(verifier); got != want {
+		t.Errorf("pkceChallenge() = %q, want %q", got, want)
+	}
+}
+
+func TestIsHeadless_SSH(t *testing.T) {
+	t.Setenv("SSH_CONNECTION", "10.0.0.1 22 10.0.0.2 22")
+
+	if !isHeadless() {
+		t.Error("isHeadless() = false over SSH, want true")
+	}
+}
+
+func TestLoginMethodFlag(t *testing.T) {
+	flag := loginCmd.Flags().Lookup("method")
+	if flag == nil {
+		t.Fatal("method flag not found")
+	}
+	if flag.DefValue != "device" {
+		t.Errorf("method default = %q, want %q", flag.DefValue, "device")
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateFrom string
+	migrateTo   string
+)
+
+var migrateCredentialsCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move stored credentials into another credential store",
+	Long: `Move every stored login from one credential store to another, then
+delete them from the source. The destination defaults to the configured
+store (credential_store in the config file, or TREK_CREDENTIAL_STORE).
+
+Stores:
+  file            plaintext JSON in ~/.trek (the default)
+  encrypted-file  AES-256-GCM; key from TREK_CREDENTIALS_PASSPHRASE or TREK_CREDENTIALS_KEY
+  helper          external trek-credential-<name> program (credential_helper)
+
+Examples:
+  TREK_CREDENTIALS_PASSPHRASE=... trek auth migrate --to encrypted-file
+  trek auth migrate --from encrypted-file --to file`,
+	RunE: runMigrateCredentials,
+}
+
+func init() {
+	authCmd.AddCommand(migrateCredentialsCmd)
+
+	migrateCredentialsCmd.Flags().StringVar(&migrateFrom, "from", credentialStoreFile, "Store to move credentials out of")
+	migrateCredentialsCmd.Flags().StringVar(&migrateTo, "to", "", "Store to move credentials into (default is the configured store)")
+}
+
+func runMigrateCredentials(cmd *cobra.Command, args []string) error {
+	to := migrateTo
+	if to == "" {
+		to = credentialStoreKind
+	}
+	if to == "" {
+		to = credentialStoreFile
+	}
+
+	src, err := newCredentialStore(migrateFrom)
+	if err != nil {
+		return err
+	}
+	dst, err := newCredentialStore(to)
+	if err != nil {
+		return err
+	}
+	if src.Name() == dst.Name() {
+		return fmt.Errorf("source and destination are both the %s store; use --to to pick another store", src.Name())
+	}
+
+	keys, err := src.List()
+	if err != nil {
+		return fmt.Errorf("failed to list credentials in %s store: %w", src.Name(), err)
+	}
+	if len(keys) == 0 {
+		fmt.Printf("No credentials found in the %s store\n", src.Name())
+		return nil
+	}
+
+	for _, key := range keys {
+		creds, err := src.Load(key)
+		if err != nil {
+			return fmt.Errorf("failed to read %q from %s store: %w", key, src.Name(), err)
+		}
+		if err := dst.Save(key, *creds); err != nil {
+			return fmt.Errorf("failed to write %q to %s store: %w", key, dst.Name(), err)
+		}
+		// Only delete the source once the copy reads back.
+		if _, err := dst.Load(key); err != nil {
+			return fmt.Errorf("failed to verify %q in %s store: %w", key, dst.Name(), err)
+		}
+		if err := src.Delete(key); err != nil {
+			return fmt.Errorf("copied %q but failed to remove it from %s store: %w", key, src.Name(), err)
+		}
+		fmt.Printf("Migrated %s: %s → %s\n", key, src.Name(), dst.Name())
+	}
+
+	configured := credentialStoreKind
+	if configured == "" {
+		configured = credentialStoreFile
+	}
+	switch {
+	case to == configured:
+	case to == credentialStoreFile:
+		fmt.Println("\nRemove credential_store from the config file (and TREK_CREDENTIAL_STORE) so trek reads from the file store.")
+	default:
+		fmt.Printf("\nSet credential_store: %s in the config file (or TREK_CREDENTIAL_STORE) so trek reads from it.\n", to)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// revokeCredentials asks the identity provider to revoke the refresh and
+// access tokens (RFC 7009) and returns the names of the tokens the provider
+// refused to revoke. When the provider can't be reached at all, a warning is
+// printed and nothing is reported as failed, so logout still works offline.
+func revokeCredentials(ctx context.Context, creds *StoredCredentials) []string {
+	if creds.RevocationEndpoint == "" {
+		if creds.RefreshToken != "" || creds.AccessToken != "" {
+			fmt.Fprintln(os.Stderr, "Warning: identity provider has no revocation endpoint; tokens stay valid until they expire")
+		}
+		return nil
+	}
+
+	// The refresh token goes first: it is the long-lived one, and many
+	// providers revoke the access tokens issued from it along with it.
+	tokens := []struct {
+		name  string
+		value string
+		hint  string
+	}{
+		{"refresh token", creds.RefreshToken, "refresh_token"},
+		{"access token", creds.AccessToken, "access_token"},
+	}
+
+	var failed []string
+	for _, tok := range tokens {
+		if tok.value == "" {
+			continue
+		}
+
+		err := revokeToken(ctx, creds.RevocationEndpoint, creds.ClientID, tok.value, tok.hint)
+		var urlErr *url.Error
+		switch {
+		case err == nil:
+			fmt.Printf("Revoked %s\n", tok.name)
+		case errors.As(err, &urlErr):
+			fmt.Fprintf(os.Stderr, "Warning: could not reach identity provider (%v); removing local credentials only\n", urlErr.Err)
+			return nil
+		default:
+			fmt.Fprintf(os.Stderr, "Error: failed to revoke %s: %v\n", tok.name, err)
+			failed = append(failed, tok.name)
+		}
+	}
+
+	return failed
+}
+
+func revokeToken(ctx context.Context, endpoint, clientID, token, hint string) error {
+	data := url.Values{}
+	data.Set("token", token)
+	data.Set("token_type_hint", hint)
+	data.Set("client_id", clientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Per RFC 7009 the provider answers 200 even for tokens that were
+	// already invalid, so anything else is a real failure.
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// revocationServer records the tokens it is asked to revoke and rejects
+// any listed in reject.
+type revocationServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	revoked map[string]string
+}
+
+func newRevocationServer(t *testing.T, reject ...string) *revocationServer {
+	t.Helper()
+	rs := &revocationServer{revoked: make(map[string]string)}
+	rejected := make(map[string]bool)
+	for _, r := range reject {
+		rejected[r] = true
+	}
+
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		token := r.PostForm.Get("token")
+		if rejected[token] {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"temporarily_unavailable"}`))
+			return
+		}
+		rs.mu.Lock()
+		rs.revoked[token] = r.PostForm.Get("token_type_hint")
+		rs.mu.Unlock()
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+func saveTestLogin(t *testing.T, revocationEndpoint string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	if err := saveCredentials(StoredCredentials{
+		AccessToken:        "access-1",
+		RefreshToken:       "refresh-1",
+		ExpiresAt:          time.Now().Add(time.Hour),
+		ClientID:           "client-123",
+		RevocationEndpoint: revocationEndpoint,
+	}); err != nil {
+		t.Fatalf("failed to save credentials: %v", err)
+	}
+}
+
+func assertCredentialsRemoved(t *testing.T) {
+	t.Helper()
+	if _, err := os.Stat(getCredentialsPath()); !os.IsNotExist(err) {
+		t.Error("credentials file still present after logout")
+	}
+}
+
+func TestLogout_RevokesBothTokens(t *testing.T) {
+	rs := newRevocationServer(t)
+	saveTestLogin(t, rs.URL)
+
+	if err := runLogout(logoutCmd, nil); err != nil {
+		t.Fatalf("runLogout() error: %v", err)
+	}
+
+	if rs.revoked["refresh-1"] != "refresh_token" {
+		t.Errorf("refresh token not revoked with refresh_token hint: %v", rs.revoked)
+	}
+	if rs.revoked["access-1"] != "access_token" {
+		t.Errorf("access token not revoked with access_token hint: %v", rs.revoked)
+	}
+	assertCredentialsRemoved(t)
+}
+
+func TestLogout_ReportsPartialFailure(t *testing.T) {
+	rs := newRevocationServer(t, "access-1")
+	saveTestLogin(t, rs.URL)
+
+	err := runLogout(logoutCmd, nil)
+	if err == nil {
+		t.Fatal("expected error when access token revocation fails")
+	}
+	if !contains(err.Error(), "access token") {
+		t.Errorf("error = %q, want it to name the access token", err.Error())
+	}
+	if contains(err.Error(), "refresh token") {
+		t.Errorf("error = %q, refresh token was revoked and should not be reported", err.Error())
+	}
+	assertCredentialsRemoved(t)
+}
+
+func TestLogout_Offline(t *testing.T) {
+	rs := newRevocationServer(t)
+	endpoint := rs.URL
+	rs.Close()
+	saveTestLogin(t, endpoint)
+
+	if err := runLogout(logoutCmd, nil); err != nil {
+		t.Fatalf("runLogout() offline should only warn, got: %v", err)
+	}
+	assertCredentialsRemoved(t)
+}
+
+func TestLogout_NotLoggedIn(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := runLogout(logoutCmd, nil); err != nil {
+		t.Errorf("runLogout() error: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ANSI colors used in table and detail output.
+const (
+	colorReset   = "\033[0m"
+	colorRed     = "\033[31m"
+	colorGreen   = "\033[32m"
+	colorYellow  = "\033[33m"
+	colorMagenta = "\033[35m"
+	colorCyan    = "\033[36m"
+	colorGray    = "\033[90m"
+)
+
+// expiringSoon is how close to expiry an active session is shown as a
+// warning.
+const expiringSoon = 5 * time.Minute
+
+// isTerminal reports whether w is a terminal; tests replace it.
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorEnabled reports whether output may contain ANSI colors. Color is off
+// with --no-color, NO_COLOR (https://no-color.org), TERM=dumb, or when
+// stdout is not a terminal.
+func colorEnabled() bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(stdout)
+}
+
+// paint wraps s in color when color is enabled.
+func paint(color, s string) string {
+	if color == "" || !colorEnabled() {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// sessionStatusColor is green for active sessions, yellow for ones about to
+// expire, gray once expired and red when revoked.
+func sessionStatusColor(status string, expiresAt time.Time) string {
+	switch status {
+	case "active":
+		if time.Until(expiresAt) < expiringSoon {
+			return colorYellow
+		}
+		return colorGreen
+	case "expired":
+		return colorGray
+	case "revoked":
+		return colorRed
+	}
+	return ""
+}
+
+func levelColor(level string) string {
+	switch level {
+	case "debug":
+		return colorCyan
+	case "trace":
+		return colorMagenta
+	}
+	return ""
+}
+
+// auditActionColor colors audit actions by their verb, e.g. session.create
+// or token.revoke.
+func auditActionColor(action string) string {
+	verb := action
+	if i := strings.LastIndexAny(action, "._"); i >= 0 {
+		verb = action[i+1:]
+	}
+	switch verb {
+	case "create", "created":
+		return colorGreen
+	case "extend", "extended", "update", "updated":
+		return colorYellow
+	case "revoke", "revoked", "delete", "deleted":
+		return colorRed
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+)
+
+var ansiPattern = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// fakeTerminal makes the printer treat its output as a terminal.
+func fakeTerminal(t *testing.T) {
+	t.Helper()
+	old := isTerminal
+	t.Cleanup(func() { isTerminal = old })
+	isTerminal = func(io.Writer) bool { return true }
+}
+
+func colorTestOutput(t *testing.T) string {
+	t.Helper()
+	buf := captureOutput(t, outputTable)
+	sessions := testSessions()
+	sessions[1].ExpiresAt = time.Now().Add(-time.Minute)
+	if err := printSessions(sessions); err != nil {
+		t.Fatalf("printSessions() error: %v", err)
+	}
+	return buf.String()
+}
+
+func TestColorDisabledIsPlainText(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+
+	tests := []struct {
+		name     string
+		terminal bool
+		noColor  bool
+		envVar   string
+	}{
+		{name: "not a terminal"},
+		{name: "--no-color", terminal: true, noColor: true},
+		{name: "NO_COLOR", terminal: true, envVar: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.terminal {
+				fakeTerminal(t)
+			}
+			noColor = tt.noColor
+			defer func() { noColor = false }()
+			t.Setenv("NO_COLOR", tt.envVar)
+
+			got := colorTestOutput(t)
+			if strings.Contains(got, "\033") {
+				t.Fatalf("output contains ANSI escapes:\n%q", got)
+			}
+
+			expires := testSessions()[0].ExpiresAt.Format("2006-01-02 15:04:05")
+			lines := strings.Split(got, "\n")
+			wantHeader := "ID      STATUS   LEVEL  EXPIRES              SELECTOR"
+			wantFirst := "sess_1  active   debug  " + expires + "  user:u123"
+			if lines[0] != wantHeader || lines[1] != wantFirst {
+				t.Errorf("table =\n%s\nwant lines\n%s\n%s", got, wantHeader, wantFirst)
+			}
+		})
+	}
+}
+
+func TestColorEnabledMatchesPlainLayout(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+	plain := colorTestOutput(t)
+
+	fakeTerminal(t)
+	colored := colorTestOutput(t)
+
+	if !strings.Contains(colored, colorGreen+"active"+colorReset) {
+		t.Errorf("active status not green:\n%q", colored)
+	}
+	if !strings.Contains(colored, colorGray+"expired"+colorReset) {
+		t.Errorf("expired status not gray:\n%q", colored)
+	}
+	if !strings.Contains(colored, colorMagenta+"trace"+colorReset) {
+		t.Errorf("trace level not magenta:\n%q", colored)
+	}
+	if stripped := ansiPattern.ReplaceAllString(colored, ""); stripped != plain {
+		t.Errorf("colored output without escapes differs from plain output:\n%s\nvs\n%s", stripped, plain)
+	}
+}
+
+func TestColorMachineFormatsStayPlain(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	fakeTerminal(t)
+
+	buf := captureOutput(t, outputJSON)
+	printSessions(testSessions())
+	if strings.Contains(buf.String(), "\033") {
+		t.Errorf("json output contains ANSI escapes:\n%s", buf)
+	}
+}
+
+func TestSessionStatusColor(t *testing.T) {
+	tests := []struct {
+		status  string
+		expires time.Duration
+		want    string
+	}{
+		{"active", time.Hour, colorGreen},
+		{"active", time.Minute, colorYellow},
+		{"expired", -time.Minute, colorGray},
+		{"revoked", time.Hour, colorRed},
+		{"pending", time.Hour, ""},
+	}
+	for _, tt := range tests {
+		if got := sessionStatusColor(tt.status, time.Now().Add(tt.expires)); got != tt.want {
+			t.Errorf("sessionStatusColor(%q, %v) = %q, want %q", tt.status, tt.expires, got, tt.want)
+		}
+	}
+}
+
+func TestAuditActionColor(t *testing.T) {
+	tests := []struct {
+		action string
+		want   string
+	}{
+		{"session.create", colorGreen},
+		{"session.extend", colorYellow},
+		{"token.revoke", colorRed},
+		{"session_revoked", colorRed},
+		{"policy.view", ""},
+	}
+	for _, tt := range tests {
+		if got := auditActionColor(tt.action); got != tt.want {
+			t.Errorf("auditActionColor(%q) = %q, want %q", tt.action, got, tt.want)
+		}
+	}
+}
+
+func TestLevelColor(t *testing.T) {
+	if levelColor(string(trek.LevelDebug)) != colorCyan || levelColor(string(trek.LevelTrace)) != colorMagenta {
+		t.Error("debug and trace levels should be cyan and magenta")
+	}
+}
@@ -0,0 +1,485 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage CLI configuration",
+	Long:  `Commands for managing named contexts and other settings in the config file.`,
+}
+
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Show the effective configuration",
+	Long: `Show each effective setting and where it came from: a flag, a TREK_*
+environment variable, the project's .trek.yaml, the active context or the
+config file. Tokens are redacted.
+
+Examples:
+  trek config view
+  trek --profile prod config view
+  trek config view -o json`,
+	Args: cobra.NoArgs,
+	RunE: runConfigView,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a value in the config file",
+	Long: `Set a key in the config file. Comments and key order are preserved.
+
+Keys: endpoint, token, org, env, credential_store, credential_helper, timeout,
+current-context, or contexts.<name>.<key> for a context's endpoint, org,
+env, auth, token or credential.
+
+Examples:
+  trek config set endpoint https://trek.example.com
+  trek config set credential_store encrypted-file
+  trek config set contexts.prod.env prod`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a value from the config file",
+	Long: `Remove a key from the config file. Takes the same keys as 'trek config set'.
+
+Examples:
+  trek config unset token
+  trek config unset contexts.prod.credential`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigUnset,
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the config file path",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fmt.Println(cfgFile)
+		return nil
+	},
+}
+
+var configGetContextsCmd = &cobra.Command{
+	Use:   "get-contexts",
+	Short: "List named contexts",
+	Long: `List the contexts defined in the config file. The current context is
+marked with *.
+
+Example:
+  trek config get-contexts`,
+	Args: cobra.NoArgs,
+	RunE: runConfigGetContexts,
+}
+
+var configUseContextCmd = &cobra.Command{
+	Use:   "use-context <name>",
+	Short: "Set the current context",
+	Long: `Set current-context in the config file, so later commands use that
+context without --profile.
+
+Example:
+  trek config use-context prod`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigUseContext,
+}
+
+var configSetContextCmd = &cobra.Command{
+	Use:   "set-context <name>",
+	Short: "Create or update a context",
+	Long: `Create a context, or update fields of an existing one. Only the flags
+given are changed.
+
+Examples:
+  trek config set-context prod --endpoint https://trek.example.com --org org_abc --env prod --auth user
+  trek config set-context ci --auth token
+  trek config set-context staging --env staging`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigSetContext,
+}
+
+var configDeleteContextCmd = &cobra.Command{
+	Use:   "delete-context <name>",
+	Short: "Delete a context",
+	Long: `Delete a context from the config file. Its stored login is revoked and
+removed unless it is the default login or another context shares the same
+credential.
+
+Example:
+  trek config delete-context staging`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigDeleteContext,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configViewCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configGetContextsCmd)
+	configCmd.AddCommand(configUseContextCmd)
+	configCmd.AddCommand(configSetContextCmd)
+	configCmd.AddCommand(configDeleteContextCmd)
+
+	configSetContextCmd.Flags().String("endpoint", "", "Trek API endpoint")
+	configSetContextCmd.Flags().String("org", "", "Organization ID")
+	configSetContextCmd.Flags().String("env", "", "Environment (dev/stage/prod)")
+	configSetContextCmd.Flags().String("auth", "", "Auth method: user (trek auth login) or token (service token)")
+	configSetContextCmd.Flags().String("credential", "", "Credential store entry for this context's login (default is the context name)")
+}
+
+// configValue is one effective setting shown by 'trek config view'.
+type configValue struct {
+	Key    string `json:"key" yaml:"key"`
+	Value  string `json:"value" yaml:"value"`
+	Source string `json:"source" yaml:"source"`
+}
+
+func effectiveConfig() []configValue {
+	source := func(key string) string {
+		return configSources[key]
+	}
+
+	values := []configValue{
+		{Key: "profile", Value: profileName, Source: source("profile")},
+		{Key: "endpoint", Value: apiEndpoint, Source: source("endpoint")},
+		{Key: "token", Value: redactToken(apiToken), Source: apiTokenSource},
+		{Key: "org", Value: orgID, Source: source("org")},
+		{Key: "env", Value: env, Source: source("env")},
+		{Key: "credential_store", Value: credentialStoreKind, Source: source("credential_store")},
+		{Key: "credential_helper", Value: credentialHelper, Source: source("credential_helper")},
+		{Key: "timeout", Value: timeout, Source: source("timeout")},
+	}
+	if apiToken == "" && !activeContext.usesToken() {
+		values[2].Value, values[2].Source = "(login credentials)", "trek auth login"
+	}
+	if credentialStoreKind == "" {
+		values[5].Value, values[5].Source = credentialStoreFile, "default"
+	}
+	if timeout == "" {
+		values[7].Value, values[7].Source = defaultTimeout.String(), "default"
+	}
+
+	// Session defaults only come from the project file.
+	session := []configValue{
+		{Key: "ttl", Value: sessionCreateCmd.Flags().Lookup("ttl").DefValue, Source: "default"},
+		{Key: "level", Value: sessionCreateCmd.Flags().Lookup("level").DefValue, Source: "default"},
+	}
+	if p := project; p != nil {
+		source := "project file " + projectFile
+		if p.Service != "" {
+			values = append(values, configValue{Key: "service", Value: p.Service, Source: source})
+		}
+		if len(p.Labels) > 0 {
+			values = append(values, configValue{Key: "labels", Value: formatLabels(p.Labels), Source: source})
+		}
+		if p.TTL != "" {
+			session[0].Value, session[0].Source = p.TTL, source
+		}
+		if p.Level != "" {
+			session[1].Value, session[1].Source = p.Level, source
+		}
+		if len(p.Templates) > 0 {
+			names := slices.Sorted(maps.Keys(p.Templates))
+			values = append(values, configValue{Key: "templates", Value: strings.Join(names, ","), Source: source})
+		}
+	}
+	return append(values, session...)
+}
+
+// formatLabels renders labels as sorted key=value pairs.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, k+"="+labels[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// redactToken keeps only the last four characters of a token.
+func redactToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	if len(token) <= 8 {
+		return "****"
+	}
+	return "****" + token[len(token)-4:]
+}
+
+var configValueColumns = []column[configValue]{
+	{Header: "KEY", Value: func(v configValue) string { return v.Key }},
+	{Header: "VALUE", Value: func(v configValue) string { return orDash(v.Value) }},
+	{Header: "SOURCE", Value: func(v configValue) string {
+		if v.Value == "" {
+			return "not set"
+		}
+		return v.Source
+	}},
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func runConfigView(cmd *cobra.Command, args []string) error {
+	if humanOutput() {
+		fmt.Fprintf(stdout, "Config file:  %s\n", cfgFile)
+		if projectFile != "" {
+			fmt.Fprintf(stdout, "Project file: %s\n", projectFile)
+		}
+		fmt.Fprintln(stdout)
+	}
+	return printList(effectiveConfig(), configValueColumns, "")
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	key, value := args[0], args[1]
+
+	doc, err := loadConfigDocument(cfgFile)
+	if err != nil {
+		return err
+	}
+	if err := doc.set(key, value); err != nil {
+		return err
+	}
+	if err := doc.save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Set %s = %s\n", key, displayConfigValue(key, value))
+
+	// A flat key has no effect while the active context sets the same key.
+	if ctx := activeContext; ctx != nil {
+		overridden := map[string]string{"endpoint": ctx.Endpoint, "org": ctx.Org, "env": ctx.Env, "token": ctx.Token}
+		if overridden[key] != "" {
+			fmt.Fprintf(os.Stderr, "Note: context %q sets its own %s, which takes precedence\n", ctx.Name, key)
+		}
+	}
+	return nil
+}
+
+// displayConfigValue masks the value of any token key, flat or in a
+// context, so 'trek config set' never echoes a secret.
+func displayConfigValue(key, value string) string {
+	if key == "token" || strings.HasSuffix(key, ".token") {
+		return redactToken(value)
+	}
+	return value
+}
+
+func runConfigUnset(cmd *cobra.Command, args []string) error {
+	key := args[0]
+
+	doc, err := loadConfigDocument(cfgFile)
+	if err != nil {
+		return err
+	}
+	removed, err := doc.unset(key)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		fmt.Printf("%s is not set in %s\n", key, cfgFile)
+		return nil
+	}
+	if err := doc.save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Unset %s\n", key)
+	return nil
+}
+
+// contextView is a context as listed by get-contexts. The token is never
+// included.
+type contextView struct {
+	Name     string `json:"name" yaml:"name"`
+	Current  bool   `json:"current" yaml:"current"`
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Org      string `json:"org,omitempty" yaml:"org,omitempty"`
+	Env      string `json:"env,omitempty" yaml:"env,omitempty"`
+	Auth     string `json:"auth,omitempty" yaml:"auth,omitempty"`
+}
+
+func (v contextView) id() string { return v.Name }
+
+var contextColumns = []column[contextView]{
+	{Header: "CURRENT", Value: func(c contextView) string {
+		if c.Current {
+			return "*"
+		}
+		return ""
+	}},
+	{Header: "NAME", Value: func(c contextView) string { return c.Name }},
+	{Header: "ENDPOINT", Value: func(c contextView) string { return c.Endpoint }},
+	{Header: "ORG", Value: func(c contextView) string { return c.Org }},
+	{Header: "ENV", Value: func(c contextView) string { return c.Env }},
+	{Header: "AUTH", Value: func(c contextView) string { return c.Auth }},
+}
+
+func runConfigGetContexts(cmd *cobra.Command, args []string) error {
+	cfg, err := readConfigFile(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	current := cfg.CurrentContext
+	if activeContext != nil {
+		current = activeContext.Name
+	}
+
+	views := make([]contextView, len(cfg.Contexts))
+	for i, c := range cfg.Contexts {
+		views[i] = contextView{
+			Name:     c.Name,
+			Current:  c.Name == current,
+			Endpoint: c.Endpoint,
+			Org:      c.Org,
+			Env:      c.Env,
+			Auth:     c.Auth,
+		}
+	}
+	return printList(views, contextColumns, "No contexts found")
+}
+
+func runConfigUseContext(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	doc, err := loadConfigDocument(cfgFile)
+	if err != nil {
+		return err
+	}
+	if node, _ := doc.context(name); node == nil {
+		return fmt.Errorf("context %q not found (see 'trek config get-contexts')", name)
+	}
+
+	setMappingScalar(doc.mapping(), "current-context", name)
+	if err := doc.save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Switched to context %q\n", name)
+	return nil
+}
+
+func runConfigSetContext(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := validateCredentialKey(name); err != nil {
+		return fmt.Errorf("invalid context name %q: use letters, digits, '.', '_' or '-'", name)
+	}
+
+	auth, _ := cmd.Flags().GetString("auth")
+	if auth != "" && auth != contextAuthUser && auth != contextAuthToken {
+		return fmt.Errorf("invalid --auth %q: expected %s or %s", auth, contextAuthUser, contextAuthToken)
+	}
+	if credential, _ := cmd.Flags().GetString("credential"); credential != "" {
+		if err := validateCredentialKey(credential); err != nil {
+			return err
+		}
+	}
+
+	doc, err := loadConfigDocument(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	node, _ := doc.context(name)
+	created := node == nil
+	if created {
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingScalar(node, "name", name)
+		seq := doc.contexts(true)
+		seq.Content = append(seq.Content, node)
+	}
+
+	for _, key := range []string{"endpoint", "org", "env", "auth", "credential"} {
+		if cmd.Flags().Changed(key) {
+			value, _ := cmd.Flags().GetString(key)
+			setMappingScalar(node, key, value)
+		}
+	}
+
+	if err := doc.save(); err != nil {
+		return err
+	}
+
+	if created {
+		fmt.Printf("Context %q created\n", name)
+	} else {
+		fmt.Printf("Context %q updated\n", name)
+	}
+	return nil
+}
+
+func runConfigDeleteContext(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	cfg, err := readConfigFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	deleted := cfg.context(name)
+	if deleted == nil {
+		return fmt.Errorf("context %q not found (see 'trek config get-contexts')", name)
+	}
+
+	doc, err := loadConfigDocument(cfgFile)
+	if err != nil {
+		return err
+	}
+	_, idx := doc.context(name)
+	seq := doc.contexts(false)
+	seq.Content = append(seq.Content[:idx], seq.Content[idx+1:]...)
+	if current := mappingValue(doc.mapping(), "current-context"); current != nil && current.Value == name {
+		deleteMappingKey(doc.mapping(), "current-context")
+	}
+	if err := doc.save(); err != nil {
+		return err
+	}
+	fmt.Printf("Context %q deleted\n", name)
+
+	// Remove the context's login unless it is the default login, or
+	// another context still uses it.
+	key := deleted.credentialKey()
+	if key == defaultCredentialKey {
+		return nil
+	}
+	for _, c := range cfg.Contexts {
+		if c.Name != name && c.credentialKey() == key {
+			return nil
+		}
+	}
+	store, err := currentCredentialStore()
+	if err != nil {
+		return err
+	}
+	creds, err := store.Load(key)
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := withTimeout(cmd.Context())
+	defer cancel()
+	revokeCredentials(ctx, creds)
+	if err := store.Delete(key); err != nil {
+		return fmt.Errorf("failed to remove credentials for %s: %w", name, err)
+	}
+	fmt.Printf("Removed stored login for %q\n", name)
+	return nil
+}
@@ -0,0 +1,384 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testContextsConfig = `# trek settings
+endpoint: https://flat.example.com
+token: flat-token
+org: org_flat
+env: dev
+current-context: staging
+contexts:
+  - name: staging # shared staging stack
+    endpoint: https://staging.example.com
+    org: org_staging
+    env: stage
+    auth: user
+  - name: ci
+    env: prod
+    auth: token
+    token: ci-token
+`
+
+// resetConfigGlobals clears the globals loadConfigFile fills and restores
+// them when the test ends.
+func resetConfigGlobals(t *testing.T) {
+	t.Helper()
+	restoreToken(t)
+	endpoint, org, environment := apiEndpoint, orgID, env
+	profile, ctx, file, sources := profileName, activeContext, cfgFile, configSources
+	store, helper := credentialStoreKind, credentialHelper
+	projFile, proj := projectFile, project
+	timeoutSetting, timeoutValue := timeout, requestTimeout
+	t.Cleanup(func() {
+		apiEndpoint, orgID, env = endpoint, org, environment
+		profileName, activeContext, cfgFile, configSources = profile, ctx, file, sources
+		credentialStoreKind, credentialHelper = store, helper
+		projectFile, project = projFile, proj
+		timeout, requestTimeout = timeoutSetting, timeoutValue
+	})
+	apiEndpoint, apiToken, apiTokenSource, orgID, env = "", "", "", "", ""
+	profileName, activeContext, configSources = "", nil, map[string]string{}
+	credentialStoreKind, credentialHelper = "", ""
+	projectFile, project = "", nil
+	timeout, requestTimeout = "", defaultTimeout
+}
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFileContexts(t *testing.T) {
+	tests := []struct {
+		name         string
+		profile      string
+		wantEndpoint string
+		wantToken    string
+		wantOrg      string
+		wantEnv      string
+	}{
+		{
+			name:         "current context",
+			wantEndpoint: "https://staging.example.com",
+			wantToken:    "", // auth: user ignores the flat token
+			wantOrg:      "org_staging",
+			wantEnv:      "stage",
+		},
+		{
+			name:         "profile falls back to flat keys",
+			profile:      "ci",
+			wantEndpoint: "https://flat.example.com",
+			wantToken:    "ci-token",
+			wantOrg:      "org_flat",
+			wantEnv:      "prod",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetConfigGlobals(t)
+			profileName = tt.profile
+
+			loadConfigFile(writeTestConfig(t, testContextsConfig))
+
+			if apiEndpoint != tt.wantEndpoint {
+				t.Errorf("apiEndpoint = %q, want %q", apiEndpoint, tt.wantEndpoint)
+			}
+			if apiToken != tt.wantToken {
+				t.Errorf("apiToken = %q, want %q", apiToken, tt.wantToken)
+			}
+			if orgID != tt.wantOrg {
+				t.Errorf("orgID = %q, want %q", orgID, tt.wantOrg)
+			}
+			if env != tt.wantEnv {
+				t.Errorf("env = %q, want %q", env, tt.wantEnv)
+			}
+		})
+	}
+}
+
+func TestResolveTokenContexts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	t.Run("unknown profile", func(t *testing.T) {
+		resetConfigGlobals(t)
+		profileName = "nope"
+		loadConfigFile(writeTestConfig(t, testContextsConfig))
+
+		_, _, err := resolveToken()
+		if err == nil || !contains(err.Error(), `context "nope" not found`) {
+			t.Errorf("resolveToken() error = %v, want context not found", err)
+		}
+	})
+
+	t.Run("token context without token", func(t *testing.T) {
+		resetConfigGlobals(t)
+		profileName = "ci"
+		loadConfigFile(writeTestConfig(t, `contexts:
+  - name: ci
+    auth: token
+`))
+
+		_, _, err := resolveToken()
+		if err == nil {
+			t.Error("resolveToken() succeeded, want error for auth: token without a token")
+		}
+	})
+
+	t.Run("per-context login", func(t *testing.T) {
+		resetConfigGlobals(t)
+		loadConfigFile(writeTestConfig(t, testContextsConfig))
+
+		creds := testCredentials()
+		creds.AccessToken = "staging-access"
+		if err := saveCredentials(creds); err != nil {
+			t.Fatalf("saveCredentials() error: %v", err)
+		}
+
+		token, _, err := resolveToken()
+		if err != nil {
+			t.Fatalf("resolveToken() error: %v", err)
+		}
+		if token != "staging-access" {
+			t.Errorf("token = %q, want %q", token, "staging-access")
+		}
+		if _, err := os.Stat(filepath.Join(trekDir(), "credentials-staging.json")); err != nil {
+			t.Errorf("expected login stored under the staging key: %v", err)
+		}
+	})
+}
+
+func TestConfigContextCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	resetConfigGlobals(t)
+	cfgFile = writeTestConfig(t, testContextsConfig)
+
+	if err := runConfigUseContext(configUseContextCmd, []string{"missing"}); err == nil {
+		t.Error("use-context of a missing context succeeded")
+	}
+	if err := runConfigUseContext(configUseContextCmd, []string{"ci"}); err != nil {
+		t.Fatalf("use-context error: %v", err)
+	}
+
+	cmd := configSetContextCmd
+	cmd.Flags().Set("env", "stage")
+	cmd.Flags().Set("auth", contextAuthUser)
+	t.Cleanup(func() {
+		cmd.Flags().Set("env", "")
+		cmd.Flags().Set("auth", "")
+		cmd.Flags().Lookup("env").Changed = false
+		cmd.Flags().Lookup("auth").Changed = false
+	})
+	if err := runConfigSetContext(cmd, []string{"qa"}); err != nil {
+		t.Fatalf("set-context error: %v", err)
+	}
+	if err := runConfigSetContext(cmd, []string{"../qa"}); err == nil {
+		t.Error("set-context accepted an invalid name")
+	}
+
+	if err := runConfigDeleteContext(configDeleteContextCmd, []string{"ci"}); err != nil {
+		t.Fatalf("delete-context error: %v", err)
+	}
+
+	cfg, err := readConfigFile(cfgFile)
+	if err != nil {
+		t.Fatalf("readConfigFile() error: %v", err)
+	}
+	if cfg.CurrentContext != "" {
+		t.Errorf("current-context = %q, want cleared after deleting it", cfg.CurrentContext)
+	}
+	if cfg.context("ci") != nil {
+		t.Error("context ci still present after delete-context")
+	}
+	qa := cfg.context("qa")
+	if qa == nil || qa.Env != "stage" || qa.Auth != contextAuthUser {
+		t.Errorf("context qa = %+v, want env stage and auth user", qa)
+	}
+
+	data, _ := os.ReadFile(cfgFile)
+	for _, comment := range []string{"# trek settings", "# shared staging stack"} {
+		if !contains(string(data), comment) {
+			t.Errorf("config lost comment %q:\n%s", comment, data)
+		}
+	}
+}
+
+func TestConfigDeleteContextKeepsDefaultLogin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	resetConfigGlobals(t)
+	cfgFile = writeTestConfig(t, testContextsConfig+`  - name: shared
+    auth: user
+    credential: default
+`)
+
+	store, err := currentCredentialStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(defaultCredentialKey, StoredCredentials{AccessToken: "flat-login"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runConfigDeleteContext(configDeleteContextCmd, []string{"shared"}); err != nil {
+		t.Fatalf("delete-context error: %v", err)
+	}
+	creds, err := store.Load(defaultCredentialKey)
+	if err != nil || creds.AccessToken != "flat-login" {
+		t.Errorf("default login after deleting a context that shares it = %v, %v; want it kept", creds, err)
+	}
+}
+
+func TestConfigViewSources(t *testing.T) {
+	resetConfigGlobals(t)
+	t.Setenv("TREK_ORG_ID", "org_env")
+	for _, name := range []string{"TREK_API_ENDPOINT", "TREK_API_TOKEN", "TREK_ENV", "TREK_PROFILE",
+		"TREK_CREDENTIAL_STORE", "TREK_CREDENTIAL_HELPER"} {
+		t.Setenv(name, "")
+	}
+	profileName = "ci"
+	cfgFile = writeTestConfig(t, testContextsConfig)
+
+	initConfig()
+
+	got := map[string]configValue{}
+	for _, v := range effectiveConfig() {
+		got[v.Key] = v
+	}
+
+	tests := []struct {
+		key        string
+		wantValue  string
+		wantSource string
+	}{
+		{"profile", "ci", "--profile flag"},
+		{"endpoint", "https://flat.example.com", "config file " + cfgFile},
+		{"token", "****", "context ci"},
+		{"org", "org_env", "TREK_ORG_ID"},
+		{"env", "prod", "context ci"},
+		{"credential_store", credentialStoreFile, "default"},
+	}
+	for _, tt := range tests {
+		if got[tt.key].Value != tt.wantValue || got[tt.key].Source != tt.wantSource {
+			t.Errorf("%s = %q from %q, want %q from %q",
+				tt.key, got[tt.key].Value, got[tt.key].Source, tt.wantValue, tt.wantSource)
+		}
+	}
+}
+
+func TestRedactToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{"", ""},
+		{"short", "****"},
+		{"trek_svc_abcdef1234", "****1234"},
+	}
+	for _, tt := range tests {
+		if got := redactToken(tt.token); got != tt.want {
+			t.Errorf("redactToken(%q) = %q, want %q", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestConfigSetUnset(t *testing.T) {
+	resetConfigGlobals(t)
+	cfgFile = writeTestConfig(t, testContextsConfig)
+
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		wantErr string
+	}{
+		{name: "top-level key", key: "org", value: "org_new"},
+		{name: "context key", key: "contexts.staging.env", value: "prod"},
+		{name: "new context", key: "contexts.qa.endpoint", value: "https://qa.example.com"},
+		{name: "dotted context name", key: "contexts.prod.eu.org", value: "org_eu"},
+		{name: "no context name", key: "contexts.org", value: "org_eu", wantErr: "expected contexts.<name>.<key>"},
+		{name: "unknown key", key: "color", value: "on", wantErr: "unknown key"},
+		{name: "unknown context key", key: "contexts.qa.color", value: "on", wantErr: "unknown context key"},
+		{name: "bad store", key: "credential_store", value: "keychain", wantErr: "unknown credential store"},
+		{name: "bad auth", key: "contexts.qa.auth", value: "password", wantErr: "invalid auth"},
+		{name: "missing current context", key: "current-context", value: "nope", wantErr: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runConfigSet(configSetCmd, []string{tt.key, tt.value})
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	if err := runConfigUnset(configUnsetCmd, []string{"token"}); err != nil {
+		t.Fatalf("unset error: %v", err)
+	}
+
+	cfg, err := readConfigFile(cfgFile)
+	if err != nil {
+		t.Fatalf("readConfigFile() error: %v", err)
+	}
+	if cfg.Org != "org_new" || cfg.Token != "" {
+		t.Errorf("org = %q, token = %q; want org_new and no token", cfg.Org, cfg.Token)
+	}
+	if c := cfg.context("staging"); c == nil || c.Env != "prod" {
+		t.Errorf("context staging = %+v, want env prod", c)
+	}
+	if c := cfg.context("qa"); c == nil || c.Endpoint != "https://qa.example.com" {
+		t.Errorf("context qa = %+v, want endpoint set", c)
+	}
+	if c := cfg.context("prod.eu"); c == nil || c.Org != "org_eu" {
+		t.Errorf("context prod.eu = %+v, want org org_eu", c)
+	}
+
+	data, _ := os.ReadFile(cfgFile)
+	if !contains(string(data), "# shared staging stack") {
+		t.Errorf("config lost its comments:\n%s", data)
+	}
+	if idx := strings.Index(string(data), "org: org_new"); idx < 0 || idx > strings.Index(string(data), "env: dev") {
+		t.Errorf("config key order changed:\n%s", data)
+	}
+}
+
+func TestDisplayConfigValue(t *testing.T) {
+	for _, key := range []string{"token", "contexts.prod.token"} {
+		if got := displayConfigValue(key, "secret-token-value"); contains(got, "secret-token-value") {
+			t.Errorf("displayConfigValue(%q) = %q, want it redacted", key, got)
+		}
+	}
+	if got := displayConfigValue("contexts.prod.env", "prod"); got != "prod" {
+		t.Errorf("displayConfigValue(env) = %q, want prod", got)
+	}
+}
+
+func TestEnvSwitchPreservesConfig(t *testing.T) {
+	resetConfigGlobals(t)
+	cfgFile = writeTestConfig(t, "# keep me\nendpoint: https://trek.example.com\nenv: dev # default env\n")
+
+	if err := runEnvSwitch(envSwitchCmd, []string{"prod"}); err != nil {
+		t.Fatalf("runEnvSwitch() error: %v", err)
+	}
+
+	data, _ := os.ReadFile(cfgFile)
+	want := "# keep me\nendpoint: https://trek.example.com\nenv: prod # default env\n"
+	if string(data) != want {
+		t.Errorf("config =\n%s\nwant\n%s", data, want)
+	}
+}
@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Values for a context's auth key.
+const (
+	contextAuthUser  = "user"
+	contextAuthToken = "token"
+)
+
+// configFile is the layout of ~/.trek/config.yaml. The flat keys predate
+// contexts and still apply when no context is active.
+type configFile struct {
+	Endpoint string `yaml:"endpoint,omitempty"`
+	Token    string `yaml:"token,omitempty"`
+	Org      string `yaml:"org,omitempty"`
+	Env      string `yaml:"env,omitempty"`
+
+	CredentialStore  string `yaml:"credential_store,omitempty"`
+	CredentialHelper string `yaml:"credential_helper,omitempty"`
+	Timeout          string `yaml:"timeout,omitempty"`
+
+	CurrentContext string          `yaml:"current-context,omitempty"`
+	Contexts       []configContext `yaml:"contexts,omitempty"`
+}
+
+// configContext is a named set of connection settings, like a kubectl
+// context. Credential names the credential store entry used for its login
+// and defaults to the context name.
+type configContext struct {
+	Name       string `yaml:"name"`
+	Endpoint   string `yaml:"endpoint,omitempty"`
+	Org        string `yaml:"org,omitempty"`
+	Env        string `yaml:"env,omitempty"`
+	Auth       string `yaml:"auth,omitempty"`
+	Token      string `yaml:"token,omitempty"`
+	Credential string `yaml:"credential,omitempty"`
+}
+
+func (cfg *configFile) context(name string) *configContext {
+	for i := range cfg.Contexts {
+		if cfg.Contexts[i].Name == name {
+			return &cfg.Contexts[i]
+		}
+	}
+	return nil
+}
+
+// credentialKey is the credential store entry holding this context's login.
+func (c *configContext) credentialKey() string {
+	if c.Credential != "" {
+		return c.Credential
+	}
+	return c.Name
+}
+
+// usesLogin reports whether the context always authenticates with login
+// credentials rather than a service token from the config file.
+func (c *configContext) usesLogin() bool {
+	return c != nil && c.Auth == contextAuthUser
+}
+
+// usesToken reports whether the context always authenticates with a
+// service token.
+func (c *configContext) usesToken() bool {
+	return c != nil && c.Auth == contextAuthToken
+}
+
+// readConfigFile parses the config file into configFile. A missing file
+// yields an empty config.
+func readConfigFile(path string) (*configFile, error) {
+	var cfg configFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// configDocument is the config file as a yaml.v3 node tree. Editing nodes
+// rather than round-tripping through a struct or map keeps the user's
+// comments and key order intact.
+type configDocument struct {
+	path string
+	root *yaml.Node
+}
+
+func loadConfigDocument(path string) (*configDocument, error) {
+	doc := &configDocument{path: path, root: &yaml.Node{Kind: yaml.DocumentNode}}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, doc.root); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	if len(doc.root.Content) == 0 {
+		doc.root.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if doc.root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse config: %s is not a YAML mapping", path)
+	}
+
+	return doc, nil
+}
+
+// mapping returns the top-level mapping of the document.
+func (d *configDocument) mapping() *yaml.Node {
+	return d.root.Content[0]
+}
+
+func (d *configDocument) save() error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.root); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	enc.Close()
+
+	if err := os.MkdirAll(filepath.Dir(d.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(d.path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// contexts returns the contexts sequence, creating it when create is set.
+func (d *configDocument) contexts(create bool) *yaml.Node {
+	seq := mappingValue(d.mapping(), "contexts")
+	if seq == nil && create {
+		seq = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(d.mapping(), "contexts", seq)
+	}
+	return seq
+}
+
+// context returns the mapping node of the named context and its index in
+// the contexts sequence, or nil and -1.
+func (d *configDocument) context(name string) (*yaml.Node, int) {
+	seq := d.contexts(false)
+	if seq == nil {
+		return nil, -1
+	}
+	for i, item := range seq.Content {
+		if v := mappingValue(item, "name"); v != nil && v.Value == name {
+			return item, i
+		}
+	}
+	return nil, -1
+}
+
+// mappingValue returns the value node for key in mapping m, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces the value for key in m, or appends the pair.
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			// Keep comments attached to the old value.
+			old := m.Content[i+1]
+			value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+}
+
+func setMappingScalar(m *yaml.Node, key, value string) {
+	setMappingValue(m, key, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+}
+
+// deleteMappingKey removes key from m and reports whether it was present.
+func deleteMappingKey(m *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// configKeys are the top-level keys 'trek config set' accepts, with an
+// optional validator for the value.
+var configKeys = map[string]func(string) error{
+	"endpoint":          nil,
+	"token":             nil,
+	"org":               nil,
+	"env":               nil,
+	"credential_store":  validateCredentialStoreKind,
+	"credential_helper": nil,
+	"timeout":           validateTimeout,
+	"current-context":   nil,
+}
+
+// contextKeys are the keys of a context, set as contexts.<name>.<key>.
+var contextKeys = map[string]func(string) error{
+	"endpoint":   nil,
+	"org":        nil,
+	"env":        nil,
+	"auth":       validateContextAuth,
+	"token":      nil,
+	"credential": validateCredentialKey,
+}
+
+func validateContextAuth(auth string) error {
+	if auth != contextAuthUser && auth != contextAuthToken {
+		return fmt.Errorf("invalid auth %q: expected %s or %s", auth, contextAuthUser, contextAuthToken)
+	}
+	return nil
+}
+
+// splitConfigKey resolves key to the mapping that holds it. Keys are either
+// top-level (env) or address a context field (contexts.prod.env); ctxName
+// is empty for top-level keys. Context names may contain dots, so the field
+// is what follows the last one.
+func splitConfigKey(key string) (ctxName, field string, err error) {
+	if rest, ok := strings.CutPrefix(key, "contexts."); ok {
+		i := strings.LastIndex(rest, ".")
+		if i <= 0 {
+			return "", "", fmt.Errorf("invalid key %q: expected contexts.<name>.<key>", key)
+		}
+		name, field := rest[:i], rest[i+1:]
+		if _, ok := contextKeys[field]; !ok {
+			return "", "", fmt.Errorf("unknown context key %q: expected one of %s", field, strings.Join(sortedKeys(contextKeys), ", "))
+		}
+		return name, field, nil
+	}
+	if _, ok := configKeys[key]; !ok {
+		return "", "", fmt.Errorf("unknown key %q: expected one of %s, or contexts.<name>.<key>", key, strings.Join(sortedKeys(configKeys), ", "))
+	}
+	return "", key, nil
+}
+
+func sortedKeys(m map[string]func(string) error) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// set validates and sets key, creating the named context if needed.
+func (d *configDocument) set(key, value string) error {
+	ctxName, field, err := splitConfigKey(key)
+	if err != nil {
+		return err
+	}
+
+	if ctxName == "" {
+		if validate := configKeys[field]; validate != nil {
+			if err := validate(value); err != nil {
+				return err
+			}
+		}
+		if field == "current-context" {
+			if node, _ := d.context(value); node == nil {
+				return fmt.Errorf("context %q not found (see 'trek config get-contexts')", value)
+			}
+		}
+		setMappingScalar(d.mapping(), field, value)
+		return nil
+	}
+
+	if validate := contextKeys[field]; validate != nil {
+		if err := validate(value); err != nil {
+			return err
+		}
+	}
+	node, _ := d.context(ctxName)
+	if node == nil {
+		if err := validateCredentialKey(ctxName); err != nil {
+			return fmt.Errorf("invalid context name %q: use letters, digits, '.', '_' or '-'", ctxName)
+		}
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingScalar(node, "name", ctxName)
+		seq := d.contexts(true)
+		seq.Content = append(seq.Content, node)
+	}
+	setMappingScalar(node, field, value)
+	return nil
+}
+
+// unset removes key and reports whether it was present.
+func (d *configDocument) unset(key string) (bool, error) {
+	ctxName, field, err := splitConfigKey(key)
+	if err != nil {
+		return false, err
+	}
+	if ctxName == "" {
+		return deleteMappingKey(d.mapping(), field), nil
+	}
+	node, _ := d.context(ctxName)
+	if node == nil {
+		return false, nil
+	}
+	return deleteMappingKey(node, field), nil
+}
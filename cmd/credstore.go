@@ -0,0 +1,464 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultCredentialKey names the credentials entry used when no other key
+// is configured.
+const defaultCredentialKey = "default"
+
+// Credential store backends, selected with credential_store in the config
+// file or TREK_CREDENTIAL_STORE.
+const (
+	credentialStoreFile      = "file"
+	credentialStoreEncrypted = "encrypted-file"
+	credentialStoreHelper    = "helper"
+)
+
+var (
+	credentialStoreKind string
+	credentialHelper    string
+)
+
+// credentialStore persists login credentials. Entries are addressed by key
+// so a single store can hold more than one login. Load returns an error
+// matching os.ErrNotExist when the entry does not exist.
+type credentialStore interface {
+	Name() string
+	Load(key string) (*StoredCredentials, error)
+	Save(key string, creds StoredCredentials) error
+	Delete(key string) error
+	List() ([]string, error)
+}
+
+// newCredentialStore returns the backend named by kind.
+func newCredentialStore(kind string) (credentialStore, error) {
+	switch kind {
+	case "", credentialStoreFile:
+		return &fileCredentialStore{dir: trekDir()}, nil
+	case credentialStoreEncrypted:
+		return newEncryptedCredentialStore(trekDir())
+	case credentialStoreHelper:
+		if credentialHelper == "" {
+			return nil, fmt.Errorf("credential_helper must be set to use the helper credential store")
+		}
+		return &helperCredentialStore{program: "trek-credential-" + credentialHelper}, nil
+	default:
+		return nil, validateCredentialStoreKind(kind)
+	}
+}
+
+func validateCredentialStoreKind(kind string) error {
+	switch kind {
+	case credentialStoreFile, credentialStoreEncrypted, credentialStoreHelper:
+		return nil
+	}
+	return fmt.Errorf("unknown credential store %q: expected %s, %s or %s",
+		kind, credentialStoreFile, credentialStoreEncrypted, credentialStoreHelper)
+}
+
+// currentCredentialStore returns the backend selected by configuration.
+func currentCredentialStore() (credentialStore, error) {
+	return newCredentialStore(credentialStoreKind)
+}
+
+func trekDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".trek")
+}
+
+var credentialKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// validateCredentialKey rejects keys that can't safely become part of a
+// file name.
+func validateCredentialKey(key string) error {
+	if !credentialKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid credential name %q: use letters, digits, '.', '_' or '-'", key)
+	}
+	return nil
+}
+
+// credentialFileName maps a key to a file name. The default key keeps the
+// historical credentials.json name so existing logins keep working.
+func credentialFileName(key, ext string) string {
+	if key == defaultCredentialKey {
+		return "credentials" + ext
+	}
+	return "credentials-" + key + ext
+}
+
+// listCredentialFiles returns the keys of credential files with the given
+// extension in dir.
+func listCredentialFiles(dir, ext string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var keys []string
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case name == "credentials"+ext:
+			keys = append(keys, defaultCredentialKey)
+		case strings.HasPrefix(name, "credentials-") && strings.HasSuffix(name, ext):
+			keys = append(keys, strings.TrimSuffix(strings.TrimPrefix(name, "credentials-"), ext))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// fileCredentialStore keeps credentials as plaintext JSON, readable only by
+// the owner.
+type fileCredentialStore struct {
+	dir string
+}
+
+func (s *fileCredentialStore) Name() string { return credentialStoreFile }
+
+func (s *fileCredentialStore) path(key string) string {
+	return filepath.Join(s.dir, credentialFileName(key, ".json"))
+}
+
+func (s *fileCredentialStore) Load(key string) (*StoredCredentials, error) {
+	if err := validateCredentialKey(key); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, err
+	}
+
+	var creds StoredCredentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, err
+	}
+
+	return &creds, nil
+}
+
+func (s *fileCredentialStore) Save(key string, creds StoredCredentials) error {
+	if err := validateCredentialKey(key); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.path(key), data, 0600)
+}
+
+func (s *fileCredentialStore) Delete(key string) error {
+	if err := validateCredentialKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *fileCredentialStore) List() ([]string, error) {
+	return listCredentialFiles(s.dir, ".json")
+}
+
+// pbkdf2Iterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256.
+const pbkdf2Iterations = 600000
+
+// derivedKeyParams identifies one PBKDF2 derivation.
+type derivedKeyParams struct {
+	passphrase string
+	salt       string
+	iterations int
+}
+
+// derivedKeys caches passphrase-derived keys for the life of the process.
+// PBKDF2 is slow on purpose, and one command may read the same credentials
+// file more than once.
+var derivedKeys = struct {
+	sync.Mutex
+	m map[derivedKeyParams][]byte
+}{m: make(map[derivedKeyParams][]byte)}
+
+// deriveKey runs PBKDF2-HMAC-SHA256, or returns the key from an earlier
+// derivation with the same parameters.
+func deriveKey(passphrase string, salt []byte, iterations int) ([]byte, error) {
+	params := derivedKeyParams{passphrase: passphrase, salt: string(salt), iterations: iterations}
+	derivedKeys.Lock()
+	defer derivedKeys.Unlock()
+	if key, ok := derivedKeys.m[params]; ok {
+		return key, nil
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	derivedKeys.m[params] = key
+	return key, nil
+}
+
+// encryptedEnvelope is the on-disk format of the encrypted-file store.
+type encryptedEnvelope struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       string `json:"salt,omitempty"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// encryptedCredentialStore encrypts credentials with AES-256-GCM. The key
+// is either given directly (TREK_CREDENTIALS_KEY, base64 of 32 bytes) or
+// derived from a passphrase (TREK_CREDENTIALS_PASSPHRASE) with PBKDF2 and a
+// per-file salt.
+type encryptedCredentialStore struct {
+	dir        string
+	key        []byte
+	passphrase string
+}
+
+func newEncryptedCredentialStore(dir string) (*encryptedCredentialStore, error) {
+	s := &encryptedCredentialStore{dir: dir}
+
+	if encoded := os.Getenv("TREK_CREDENTIALS_KEY"); encoded != "" {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("TREK_CREDENTIALS_KEY must be 32 bytes, base64-encoded")
+		}
+		s.key = key
+		return s, nil
+	}
+
+	s.passphrase = os.Getenv("TREK_CREDENTIALS_PASSPHRASE")
+	if s.passphrase == "" {
+		return nil, fmt.Errorf("the %s credential store needs TREK_CREDENTIALS_PASSPHRASE or TREK_CREDENTIALS_KEY", credentialStoreEncrypted)
+	}
+	return s, nil
+}
+
+func (s *encryptedCredentialStore) Name() string { return credentialStoreEncrypted }
+
+func (s *encryptedCredentialStore) path(key string) string {
+	return filepath.Join(s.dir, credentialFileName(key, ".enc"))
+}
+
+func (s *encryptedCredentialStore) aead(env *encryptedEnvelope) (cipher.AEAD, error) {
+	key := s.key
+	if env.KDF == "pbkdf2-sha256" {
+		if s.passphrase == "" {
+			return nil, fmt.Errorf("credentials were encrypted with a passphrase; set TREK_CREDENTIALS_PASSPHRASE")
+		}
+		salt, err := base64.StdEncoding.DecodeString(env.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid salt: %w", err)
+		}
+		key, err = deriveKey(s.passphrase, salt, env.Iterations)
+		if err != nil {
+			return nil, err
+		}
+	} else if key == nil {
+		return nil, fmt.Errorf("credentials were encrypted with a raw key; set TREK_CREDENTIALS_KEY")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *encryptedCredentialStore) Load(key string) (*StoredCredentials, error) {
+	if err := validateCredentialKey(key); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, err
+	}
+
+	var env encryptedEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("invalid encrypted credentials file: %w", err)
+	}
+	aead, err := s.aead(&env)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(env.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(env.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %w", err)
+	}
+
+	// The key name is bound as additional data so entries can't be swapped.
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credentials (wrong passphrase or key?)")
+	}
+
+	var creds StoredCredentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return nil, err
+	}
+
+	return &creds, nil
+}
+
+func (s *encryptedCredentialStore) Save(key string, creds StoredCredentials) error {
+	if err := validateCredentialKey(key); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+
+	env := encryptedEnvelope{Version: 1, KDF: "none"}
+	if s.key == nil {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		env.KDF = "pbkdf2-sha256"
+		env.Iterations = pbkdf2Iterations
+		env.Salt = base64.StdEncoding.EncodeToString(salt)
+	}
+
+	aead, err := s.aead(&env)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	env.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	env.Ciphertext = base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plaintext, []byte(key)))
+
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.path(key), data, 0600)
+}
+
+func (s *encryptedCredentialStore) Delete(key string) error {
+	if err := validateCredentialKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *encryptedCredentialStore) List() ([]string, error) {
+	return listCredentialFiles(s.dir, ".enc")
+}
+
+// helperCredentialStore delegates to an external program named
+// trek-credential-<name> on PATH, which can keep secrets in an OS keyring,
+// a password manager or a vault. The protocol is:
+//
+//	get <key>     print the credentials JSON; print nothing if not found
+//	store <key>   read the credentials JSON from stdin
+//	erase <key>   delete the entry
+//	list          print one key per line
+//
+// Secrets only travel over stdin and stdout, never on the command line.
+type helperCredentialStore struct {
+	program string
+}
+
+func (s *helperCredentialStore) Name() string { return credentialStoreHelper }
+
+func (s *helperCredentialStore) run(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(s.program, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return nil, fmt.Errorf("%s %s: %w", s.program, args[0], err)
+		}
+		return nil, fmt.Errorf("%s %s: %w: %s", s.program, args[0], err, msg)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func (s *helperCredentialStore) Load(key string) (*StoredCredentials, error) {
+	out, err := s.run(nil, "get", key)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, fmt.Errorf("credentials %q: %w", key, os.ErrNotExist)
+	}
+
+	var creds StoredCredentials
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return nil, fmt.Errorf("%s returned invalid credentials: %w", s.program, err)
+	}
+
+	return &creds, nil
+}
+
+func (s *helperCredentialStore) Save(key string, creds StoredCredentials) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	_, err = s.run(data, "store", key)
+	return err
+}
+
+func (s *helperCredentialStore) Delete(key string) error {
+	_, err := s.run(nil, "erase", key)
+	return err
+}
+
+func (s *helperCredentialStore) List() ([]string, error) {
+	out, err := s.run(nil, "list")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
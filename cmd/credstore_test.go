@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCredentials() StoredCredentials {
+	return StoredCredentials{
+		AccessToken:  "access-1",
+		RefreshToken: "refresh-1",
+		ExpiresAt:    time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		Email:        "dev@example.com",
+	}
+}
+
+// exerciseStore runs a store through save, load, list and delete.
+func exerciseStore(t *testing.T, store credentialStore) {
+	t.Helper()
+
+	if _, err := store.Load("staging"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Load() of missing entry error = %v, want os.ErrNotExist", err)
+	}
+
+	want := testCredentials()
+	if err := store.Save(defaultCredentialKey, want); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := store.Save("staging", want); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	got, err := store.Load(defaultCredentialKey)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got.AccessToken != want.AccessToken || got.RefreshToken != want.RefreshToken || got.Email != want.Email {
+		t.Errorf("Load() = %+v, want %+v", got, want)
+	}
+
+	keys, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if strings.Join(keys, ",") != "default,staging" {
+		t.Errorf("List() = %v, want [default staging]", keys)
+	}
+
+	if err := store.Delete("staging"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if _, err := store.Load("staging"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load() after Delete() error = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestFileCredentialStore(t *testing.T) {
+	exerciseStore(t, &fileCredentialStore{dir: t.TempDir()})
+}
+
+func TestEncryptedCredentialStore_Passphrase(t *testing.T) {
+	t.Setenv("TREK_CREDENTIALS_KEY", "")
+	t.Setenv("TREK_CREDENTIALS_PASSPHRASE", "correct horse battery staple")
+	dir := t.TempDir()
+
+	store, err := newEncryptedCredentialStore(dir)
+	if err != nil {
+		t.Fatalf("newEncryptedCredentialStore() error: %v", err)
+	}
+	exerciseStore(t, store)
+
+	data, err := os.ReadFile(filepath.Join(dir, "credentials.enc"))
+	if err != nil {
+		t.Fatalf("failed to read encrypted file: %v", err)
+	}
+	if strings.Contains(string(data), "access-1") {
+		t.Error("encrypted file contains the plaintext access token")
+	}
+}
+
+func TestEncryptedCredentialStore_WrongPassphrase(t *testing.T) {
+	t.Setenv("TREK_CREDENTIALS_KEY", "")
+	dir := t.TempDir()
+
+	t.Setenv("TREK_CREDENTIALS_PASSPHRASE", "right")
+	store, _ := newEncryptedCredentialStore(dir)
+	store.Save(defaultCredentialKey, testCredentials())
+
+	t.Setenv("TREK_CREDENTIALS_PASSPHRASE", "wrong")
+	store, _ = newEncryptedCredentialStore(dir)
+	_, err := store.Load(defaultCredentialKey)
+	if err == nil || !contains(err.Error(), "failed to decrypt") {
+		t.Errorf("Load() error = %v, want decrypt failure", err)
+	}
+}
+
+func TestEncryptedCredentialStore_RawKey(t *testing.T) {
+	t.Setenv("TREK_CREDENTIALS_PASSPHRASE", "")
+	t.Setenv("TREK_CREDENTIALS_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+
+	store, err := newEncryptedCredentialStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newEncryptedCredentialStore() error: %v", err)
+	}
+	exerciseStore(t, store)
+}
+
+func TestEncryptedCredentialStore_RequiresSecret(t *testing.T) {
+	t.Setenv("TREK_CREDENTIALS_PASSPHRASE", "")
+	t.Setenv("TREK_CREDENTIALS_KEY", "")
+
+	if _, err := newEncryptedCredentialStore(t.TempDir()); err == nil {
+		t.Error("expected error without passphrase or key")
+	}
+}
+
+// installTestHelper puts a trek-credential-test program on PATH that keeps
+// entries as files in a temp directory.
+func installTestHelper(t *testing.T) {
+	t.Helper()
+	binDir := t.TempDir()
+	dataDir := t.TempDir()
+
+	script := `#!/bin/sh
+dir="` + dataDir + `"
+case "$1" in
+get)   [ -f "$dir/$2" ] && cat "$dir/$2"; exit 0 ;;
+store) cat > "$dir/$2" ;;
+erase) rm -f "$dir/$2" ;;
+list)  ls "$dir" ;;
+*)     echo "unknown action $1" >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(binDir, "trek-credential-test"), []byte(script), 0700); err != nil {
+		t.Fatalf("failed to write helper: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestHelperCredentialStore(t *testing.T) {
+	installTestHelper(t)
+
+	exerciseStore(t, &helperCredentialStore{program: "trek-credential-test"})
+}
+
+func TestHelperCredentialStore_MissingProgram(t *testing.T) {
+	store := &helperCredentialStore{program: "trek-credential-does-not-exist"}
+
+	if _, err := store.Load(defaultCredentialKey); err == nil {
+		t.Error("expected error for missing helper program")
+	}
+}
+
+func TestNewCredentialStore(t *testing.T) {
+	credentialHelper = ""
+	defer func() { credentialHelper = "" }()
+
+	if _, err := newCredentialStore("keychain"); err == nil {
+		t.Error("expected error for unknown store")
+	}
+	if _, err := newCredentialStore(credentialStoreHelper); err == nil {
+		t.Error("expected error for helper store without credential_helper")
+	}
+	store, err := newCredentialStore("")
+	if err != nil || store.Name() != credentialStoreFile {
+		t.Errorf("newCredentialStore(\"\") = %v, %v; want file store", store, err)
+	}
+}
+
+func TestMigrateCredentials(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TREK_CREDENTIALS_KEY", "")
+	t.Setenv("TREK_CREDENTIALS_PASSPHRASE", "secret")
+	credentialStoreKind = ""
+
+	if err := saveCredentials(testCredentials()); err != nil {
+		t.Fatalf("saveCredentials() error: %v", err)
+	}
+
+	migrateFrom, migrateTo = credentialStoreFile, credentialStoreEncrypted
+	defer func() { migrateFrom, migrateTo = credentialStoreFile, "" }()
+
+	if err := runMigrateCredentials(migrateCredentialsCmd, nil); err != nil {
+		t.Fatalf("runMigrateCredentials() error: %v", err)
+	}
+
+	if _, err := os.Stat(getCredentialsPath()); !os.IsNotExist(err) {
+		t.Error("plaintext credentials still present after migration")
+	}
+
+	credentialStoreKind = credentialStoreEncrypted
+	defer func() { credentialStoreKind = "" }()
+	creds, err := loadCredentials()
+	if err != nil {
+		t.Fatalf("loadCredentials() from encrypted store error: %v", err)
+	}
+	if creds.AccessToken != "access-1" {
+		t.Errorf("AccessToken = %q, want %q", creds.AccessToken, "access-1")
+	}
+}
+
+func TestDeriveKeyIsCached(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	first, err := deriveKey("passphrase", salt, 1000)
+	if err != nil {
+		t.Fatalf("deriveKey() error: %v", err)
+	}
+	second, err := deriveKey("passphrase", salt, 1000)
+	if err != nil {
+		t.Fatalf("deriveKey() error: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("cached key differs from the derived key")
+	}
+	if _, ok := derivedKeys.m[derivedKeyParams{passphrase: "passphrase", salt: string(salt), iterations: 1000}]; !ok {
+		t.Error("derived key was not cached")
+	}
+
+	other, err := deriveKey("other", salt, 1000)
+	if err != nil {
+		t.Fatalf("deriveKey() error: %v", err)
+	}
+	if bytes.Equal(first, other) {
+		t.Error("different passphrases derived the same key")
+	}
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+	"github.com/spf13/cobra"
+)
+
+var (
+	devAddr               string
+	devRequireToken       string
+	devMaxTTL             time.Duration
+	devRequireReason      bool
+	devAllowEmptySelector bool
+	devAllowedKeys        []string
+	devMaxEventsPerReq    int
+	devMaxEventsPerSess   int
+	devEnvironments       []string
+)
+
+var devCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "Local development tools",
+	Long:  `Commands for developing against Trek without a control plane.`,
+}
+
+var devServerCmd = &cobra.Command{
+	Use:   "server",
+	Short: "Run an in-memory Trek control plane",
+	Long: `Run a local, in-memory Trek control plane for trying the CLI offline:
+sessions, active sessions, policies, tokens, environments and audit events.
+Policy flags are enforced on session create and extend. Everything is lost
+when the server stops.
+
+The routes, query parameters and JSON envelopes are the ones trek-go's
+client uses, including ETags on the active-sessions snapshot SDKs poll, so
+an SDK integration can be pointed at the server as well as the CLI.
+
+Examples:
+  trek dev server
+  trek dev server --addr 127.0.0.1:9000 --require-token dev-token --require-reason
+  trek dev server --max-ttl 30m --allowed-selector-keys user_id,route
+  trek dev server --max-events-per-request 100 --max-events-per-session 1000
+
+Then, in another shell:
+  export TREK_API_ENDPOINT=http://127.0.0.1:8787 TREK_API_TOKEN=dev TREK_ORG_ID=org_dev TREK_ENV=dev
+  trek session create --user u123 --ttl 10m`,
+	Args: cobra.NoArgs,
+	RunE: runDevServer,
+}
+
+func init() {
+	rootCmd.AddCommand(devCmd)
+	devCmd.AddCommand(devServerCmd)
+
+	devServerCmd.Flags().StringVar(&devAddr, "addr", "127.0.0.1:8787", "Address to listen on")
+	devServerCmd.Flags().StringVar(&devRequireToken, "require-token", "", "Bearer token clients must send (default accepts any)")
+	devServerCmd.Flags().DurationVar(&devMaxTTL, "max-ttl", time.Hour, "Policy: maximum session TTL")
+	devServerCmd.Flags().BoolVar(&devRequireReason, "require-reason", false, "Policy: require a reason for new sessions")
+	devServerCmd.Flags().BoolVar(&devAllowEmptySelector, "allow-empty-selector", false, "Policy: allow sessions without a selector")
+	devServerCmd.Flags().StringSliceVar(&devAllowedKeys, "allowed-selector-keys", nil, "Policy: selector keys sessions may use, e.g. user_id,route (default any)")
+	devServerCmd.Flags().IntVar(&devMaxEventsPerReq, "max-events-per-request", 0, "Policy: default and maximum debug events per request (default unlimited)")
+	devServerCmd.Flags().IntVar(&devMaxEventsPerSess, "max-events-per-session", 0, "Policy: default and maximum debug events per session (default unlimited)")
+	devServerCmd.Flags().StringSliceVar(&devEnvironments, "environments", []string{"dev", "stage", "prod"}, "Environments to list")
+}
+
+func runDevServer(cmd *cobra.Command, args []string) error {
+	if devMaxEventsPerReq < 0 || devMaxEventsPerSess < 0 {
+		return usageError("--max-events-per-request and --max-events-per-session must not be negative")
+	}
+	policy := trek.Policy{
+		MaxTTLSeconds:       int(devMaxTTL.Seconds()),
+		RequireReason:       devRequireReason,
+		AllowEmptySelector:  devAllowEmptySelector,
+		AllowedSelectorKeys: devAllowedKeys,
+		DefaultCaps: trek.Caps{
+			MaxDebugEventsPerRequest: devMaxEventsPerReq,
+			MaxDebugEventsPerSession: devMaxEventsPerSess,
+		},
+	}
+	srv := newDevServer(policy, devRequireToken, devEnvironments)
+	srv.log = os.Stderr
+
+	ln, err := net.Listen("tcp", devAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", devAddr, err)
+	}
+	httpServer := &http.Server{Handler: srv.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	fmt.Printf("Trek dev server listening on http://%s (Ctrl+C to stop)\n", ln.Addr())
+	token := devRequireToken
+	if token == "" {
+		token = "dev"
+	}
+	fmt.Printf("  export TREK_API_ENDPOINT=http://%s TREK_API_TOKEN=%s TREK_ORG_ID=org_dev TREK_ENV=dev\n\n", ln.Addr(), token)
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	done := make(chan error, 1)
+	go func() { done <- httpServer.Serve(ln) }()
+
+	select {
+	case err := <-done:
+		return fmt.Errorf("dev server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to stop dev server: %w", err)
+	}
+	fmt.Println("Dev server stopped")
+	return nil
+}
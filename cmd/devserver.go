@@ -0,0 +1,511 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+)
+
+const (
+	// devAuditPageSize is how many audit events are returned per page.
+	devAuditPageSize = 50
+
+	// devUser is the actor of every call; the dev server has no users.
+	devUser = "dev-user"
+)
+
+// devServer is an in-memory Trek control plane for 'trek dev server' and
+// end-to-end tests. It serves the endpoints trek.Client calls, with the
+// SDK's paths, query parameters and JSON envelopes, and enforces the policy
+// it was started with; nothing is persisted.
+type devServer struct {
+	policy       trek.Policy
+	token        string // bearer token required on every call; "" accepts any
+	environments []string
+	now          func() time.Time
+	log          io.Writer
+
+	mu       sync.Mutex
+	sessions []*devSession
+	tokens   []trek.Token
+	events   []trek.AuditEvent
+	version  int // bumped on every session change, for active-sessions ETags
+	nextID   int
+}
+
+type devSession struct {
+	trek.Session
+	revoked bool
+}
+
+// devError is the JSON body of every error response.
+type devError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id"`
+}
+
+func newDevServer(policy trek.Policy, token string, environments []string) *devServer {
+	return &devServer{
+		policy:       policy,
+		token:        token,
+		environments: environments,
+		now:          time.Now,
+	}
+}
+
+// handler routes the API. TestDevServerServesSDKClient calls every route
+// through trek.Client, so a path or envelope that drifts from the SDK fails
+// there.
+func (s *devServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/sessions", s.createSession)
+	mux.HandleFunc("GET /v1/sessions", s.listSessions)
+	mux.HandleFunc("GET /v1/sessions/{id}", s.getSession)
+	mux.HandleFunc("POST /v1/sessions/{id}/extend", s.extendSession)
+	mux.HandleFunc("PATCH /v1/sessions/{id}", s.updateSession)
+	mux.HandleFunc("DELETE /v1/sessions/{id}", s.revokeSession)
+	mux.HandleFunc("GET /v1/active-sessions", s.activeSessions)
+	mux.HandleFunc("GET /v1/policy", s.getPolicy)
+	mux.HandleFunc("POST /v1/tokens", s.createToken)
+	mux.HandleFunc("GET /v1/tokens", s.listTokens)
+	mux.HandleFunc("DELETE /v1/tokens/{id}", s.revokeToken)
+	mux.HandleFunc("GET /v1/environments", s.listEnvironments)
+	mux.HandleFunc("GET /v1/audit-events", s.listAuditEvents)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		rec.Header().Set("X-Request-Id", s.newID("req"))
+		if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+			writeDevError(rec, http.StatusUnauthorized, "unauthenticated", "missing or invalid bearer token")
+		} else {
+			mux.ServeHTTP(rec, r)
+		}
+		if s.log != nil {
+			fmt.Fprintf(s.log, "%s %s %d\n", r.Method, r.URL.RequestURI(), rec.status)
+		}
+	})
+}
+
+func (s *devServer) createSession(w http.ResponseWriter, r *http.Request) {
+	var req trek.CreateSessionRequest
+	if !decodeDevRequest(w, r, &req) {
+		return
+	}
+	if req.Level != trek.LevelDebug && req.Level != trek.LevelTrace {
+		writeDevError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("level must be debug or trace, got %q", req.Level))
+		return
+	}
+	if req.TTLSeconds <= 0 {
+		writeDevError(w, http.StatusBadRequest, "invalid_request", "ttl_seconds must be positive")
+		return
+	}
+	if req.Caps != nil && (req.Caps.MaxDebugEventsPerRequest < 0 || req.Caps.MaxDebugEventsPerSession < 0) {
+		writeDevError(w, http.StatusBadRequest, "invalid_request", "caps must not be negative")
+		return
+	}
+	if code, msg := s.checkPolicy(req); code != "" {
+		writeDevError(w, http.StatusUnprocessableEntity, code, msg)
+		return
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	sess := &devSession{Session: trek.Session{
+		ID:        s.newIDLocked("sess"),
+		Selector:  req.Selector,
+		Level:     req.Level,
+		ExpiresAt: now.Add(time.Duration(req.TTLSeconds) * time.Second),
+		Labels:    req.Labels,
+		Caps:      s.policy.DefaultCaps,
+		Reason:    req.Reason,
+		CreatedBy: devUser,
+		CreatedAt: now,
+	}}
+	if req.Caps != nil {
+		sess.Caps = mergeCaps(sess.Caps, *req.Caps)
+	}
+	s.sessions = append(s.sessions, sess)
+	s.version++
+	s.auditLocked("session.create", "session", sess.ID)
+	s.mu.Unlock()
+
+	writeDevJSON(w, http.StatusCreated, trek.CreateSessionResponse{ID: sess.ID, Status: "active", ExpiresAt: sess.ExpiresAt})
+}
+
+// checkPolicy returns the policy error code and message for req, or "" if
+// the policy allows it.
+func (s *devServer) checkPolicy(req trek.CreateSessionRequest) (string, string) {
+	p := s.policy
+	if p.MaxTTLSeconds > 0 && req.TTLSeconds > p.MaxTTLSeconds {
+		return "policy_max_ttl", fmt.Sprintf("ttl %ds exceeds the policy maximum of %ds", req.TTLSeconds, p.MaxTTLSeconds)
+	}
+	if p.RequireReason && strings.TrimSpace(req.Reason) == "" {
+		return "policy_reason_required", "policy requires a reason"
+	}
+	if trek.IsEmptySelector(req.Selector) && !p.AllowEmptySelector {
+		return "policy_empty_selector", "policy does not allow an empty selector"
+	}
+	if len(p.AllowedSelectorKeys) > 0 {
+		for _, key := range selectorKeys(req.Selector) {
+			if !slices.Contains(p.AllowedSelectorKeys, key) {
+				return "policy_selector_key", fmt.Sprintf("selector key %q is not allowed by policy (allowed: %s)", key, strings.Join(p.AllowedSelectorKeys, ", "))
+			}
+		}
+	}
+	if req.Caps != nil {
+		return checkDevCaps(*req.Caps, p.DefaultCaps)
+	}
+	return "", ""
+}
+
+// checkDevCaps rejects caps above the policy's defaults, which are the most
+// a session may ask for.
+func checkDevCaps(caps, limits trek.Caps) (string, string) {
+	if limits.MaxDebugEventsPerRequest > 0 && caps.MaxDebugEventsPerRequest > limits.MaxDebugEventsPerRequest {
+		return "policy_caps", fmt.Sprintf("max_debug_events_per_request %d exceeds the policy limit of %d", caps.MaxDebugEventsPerRequest, limits.MaxDebugEventsPerRequest)
+	}
+	if limits.MaxDebugEventsPerSession > 0 && caps.MaxDebugEventsPerSession > limits.MaxDebugEventsPerSession {
+		return "policy_caps", fmt.Sprintf("max_debug_events_per_session %d exceeds the policy limit of %d", caps.MaxDebugEventsPerSession, limits.MaxDebugEventsPerSession)
+	}
+	return "", ""
+}
+
+// mergeCaps returns base with the non-zero fields of update applied.
+func mergeCaps(base, update trek.Caps) trek.Caps {
+	if update.MaxDebugEventsPerRequest > 0 {
+		base.MaxDebugEventsPerRequest = update.MaxDebugEventsPerRequest
+	}
+	if update.MaxDebugEventsPerSession > 0 {
+		base.MaxDebugEventsPerSession = update.MaxDebugEventsPerSession
+	}
+	return base
+}
+
+func (s *devServer) listSessions(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "active" && status != "expired" && status != "revoked" {
+		writeDevError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("unknown status %q", status))
+		return
+	}
+
+	s.mu.Lock()
+	sessions := []trek.Session{}
+	for _, sess := range s.sessions {
+		if status == "" || s.statusLocked(sess) == status {
+			sessions = append(sessions, s.viewLocked(sess))
+		}
+	}
+	s.mu.Unlock()
+
+	writeDevJSON(w, http.StatusOK, map[string]any{"sessions": sessions})
+}
+
+func (s *devServer) getSession(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessionLocked(r.PathValue("id"))
+	if sess == nil {
+		writeDevNotFound(w, "session", r.PathValue("id"))
+		return
+	}
+	writeDevJSON(w, http.StatusOK, s.viewLocked(sess))
+}
+
+func (s *devServer) extendSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TTLSeconds int `json:"ttl_seconds"`
+	}
+	if !decodeDevRequest(w, r, &req) {
+		return
+	}
+	if req.TTLSeconds <= 0 {
+		writeDevError(w, http.StatusBadRequest, "invalid_request", "ttl_seconds must be positive")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessionLocked(r.PathValue("id"))
+	if sess == nil {
+		writeDevNotFound(w, "session", r.PathValue("id"))
+		return
+	}
+	if status := s.statusLocked(sess); status != "active" {
+		writeDevError(w, http.StatusBadRequest, "session_not_active", fmt.Sprintf("session %s is %s", sess.ID, status))
+		return
+	}
+	// Extending adds to the current expiry; the policy maximum bounds the
+	// time left afterwards.
+	expiresAt := sess.ExpiresAt.Add(time.Duration(req.TTLSeconds) * time.Second)
+	if maxTTL := s.policy.MaxTTLSeconds; maxTTL > 0 && expiresAt.Sub(s.now()) > time.Duration(maxTTL)*time.Second {
+		writeDevError(w, http.StatusUnprocessableEntity, "policy_max_ttl", fmt.Sprintf("extending by %ds would leave more than the policy maximum of %ds", req.TTLSeconds, maxTTL))
+		return
+	}
+	sess.ExpiresAt = expiresAt
+	s.version++
+	s.auditLocked("session.extend", "session", sess.ID)
+
+	writeDevJSON(w, http.StatusOK, trek.ExtendSessionResponse{ID: sess.ID, ExpiresAt: sess.ExpiresAt})
+}
+
+func (s *devServer) updateSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Caps trek.Caps `json:"caps"`
+	}
+	if !decodeDevRequest(w, r, &req) {
+		return
+	}
+	if req.Caps.MaxDebugEventsPerRequest < 0 || req.Caps.MaxDebugEventsPerSession < 0 {
+		writeDevError(w, http.StatusBadRequest, "invalid_request", "caps must not be negative")
+		return
+	}
+	if code, msg := checkDevCaps(req.Caps, s.policy.DefaultCaps); code != "" {
+		writeDevError(w, http.StatusUnprocessableEntity, code, msg)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessionLocked(r.PathValue("id"))
+	if sess == nil {
+		writeDevNotFound(w, "session", r.PathValue("id"))
+		return
+	}
+	if status := s.statusLocked(sess); status != "active" {
+		writeDevError(w, http.StatusBadRequest, "session_not_active", fmt.Sprintf("session %s is %s", sess.ID, status))
+		return
+	}
+	sess.Caps = mergeCaps(sess.Caps, req.Caps)
+	s.version++
+	s.auditLocked("session.update", "session", sess.ID)
+
+	writeDevJSON(w, http.StatusOK, s.viewLocked(sess))
+}
+
+func (s *devServer) revokeSession(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessionLocked(r.PathValue("id"))
+	if sess == nil {
+		writeDevNotFound(w, "session", r.PathValue("id"))
+		return
+	}
+	if !sess.revoked {
+		// Revoking ends the session now, so clients that only look at the
+		// expiry stop matching it too.
+		sess.revoked = true
+		if now := s.now(); sess.ExpiresAt.After(now) {
+			sess.ExpiresAt = now
+		}
+		s.version++
+		s.auditLocked("session.revoke", "session", sess.ID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// activeSessions is the endpoint SDKs poll. It returns 304 when the
+// caller's If-None-Match matches the current version.
+func (s *devServer) activeSessions(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+
+	s.mu.Lock()
+	etag := fmt.Sprintf(`"v%d"`, s.version)
+	sessions := []trek.Session{}
+	for _, sess := range s.sessions {
+		if s.statusLocked(sess) != "active" {
+			continue
+		}
+		if svc, ok := sess.Labels["service"]; ok && service != "" && svc != service {
+			continue
+		}
+		sessions = append(sessions, s.viewLocked(sess))
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeDevJSON(w, http.StatusOK, trek.ActiveSessionsResponse{Sessions: sessions})
+}
+
+func (s *devServer) getPolicy(w http.ResponseWriter, r *http.Request) {
+	writeDevJSON(w, http.StatusOK, s.policy)
+}
+
+func (s *devServer) createToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if !decodeDevRequest(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeDevError(w, http.StatusBadRequest, "invalid_request", "name is required")
+		return
+	}
+	secret := make([]byte, 16)
+	rand.Read(secret)
+
+	s.mu.Lock()
+	tok := trek.Token{ID: s.newIDLocked("tok"), Name: req.Name, CreatedAt: s.now()}
+	s.tokens = append(s.tokens, tok)
+	s.auditLocked("token.create", "token", tok.ID)
+	s.mu.Unlock()
+
+	writeDevJSON(w, http.StatusCreated, trek.CreateTokenResponse{ID: tok.ID, Name: tok.Name, Token: "trek_dev_" + hex.EncodeToString(secret)})
+}
+
+func (s *devServer) listTokens(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	tokens := slices.Clone(s.tokens)
+	s.mu.Unlock()
+	if tokens == nil {
+		tokens = []trek.Token{}
+	}
+	writeDevJSON(w, http.StatusOK, map[string]any{"tokens": tokens})
+}
+
+func (s *devServer) revokeToken(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.tokens, func(t trek.Token) bool { return t.ID == id })
+	if i < 0 {
+		writeDevNotFound(w, "token", id)
+		return
+	}
+	s.tokens = slices.Delete(s.tokens, i, i+1)
+	s.auditLocked("token.revoke", "token", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *devServer) listEnvironments(w http.ResponseWriter, r *http.Request) {
+	envs := make([]trek.Environment, len(s.environments))
+	for i, name := range s.environments {
+		envs[i] = trek.Environment{ID: "env_" + name, Name: name}
+	}
+	writeDevJSON(w, http.StatusOK, map[string]any{"environments": envs})
+}
+
+// listAuditEvents pages through events newest first. The cursor is the
+// offset of the next page.
+func (s *devServer) listAuditEvents(w http.ResponseWriter, r *http.Request) {
+	offset := 0
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		n, err := strconv.Atoi(cursor)
+		if err != nil || n < 0 {
+			writeDevError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("invalid cursor %q", cursor))
+			return
+		}
+		offset = n
+	}
+
+	s.mu.Lock()
+	events := slices.Clone(s.events)
+	s.mu.Unlock()
+	slices.Reverse(events)
+
+	resp := trek.ListAuditEventsResponse{Events: []trek.AuditEvent{}}
+	if offset < len(events) {
+		end := min(offset+devAuditPageSize, len(events))
+		resp.Events = events[offset:end]
+		if end < len(events) {
+			resp.NextCursor = strconv.Itoa(end)
+		}
+	}
+	writeDevJSON(w, http.StatusOK, resp)
+}
+
+func (s *devServer) sessionLocked(id string) *devSession {
+	for _, sess := range s.sessions {
+		if sess.ID == id {
+			return sess
+		}
+	}
+	return nil
+}
+
+// viewLocked is sess as the API returns it, with its current status.
+func (s *devServer) viewLocked(sess *devSession) trek.Session {
+	out := sess.Session
+	out.Status = s.statusLocked(sess)
+	return out
+}
+
+func (s *devServer) statusLocked(sess *devSession) string {
+	switch {
+	case sess.revoked:
+		return "revoked"
+	case !s.now().Before(sess.ExpiresAt):
+		return "expired"
+	}
+	return "active"
+}
+
+func (s *devServer) auditLocked(action, targetType, targetID string) {
+	s.events = append(s.events, trek.AuditEvent{
+		ID:          s.newIDLocked("evt"),
+		Action:      action,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		ActorUserID: devUser,
+		CreatedAt:   s.now(),
+	})
+}
+
+func (s *devServer) newID(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.newIDLocked(prefix)
+}
+
+func (s *devServer) newIDLocked(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s_%d", prefix, s.nextID)
+}
+
+func decodeDevRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeDevError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("invalid JSON body: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeDevJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeDevError(w http.ResponseWriter, status int, code, message string) {
+	writeDevJSON(w, status, devError{Code: code, Message: message, RequestID: w.Header().Get("X-Request-Id")})
+}
+
+func writeDevNotFound(w http.ResponseWriter, kind, id string) {
+	writeDevError(w, http.StatusNotFound, "not_found", fmt.Sprintf("%s %s not found", kind, id))
+}
+
+// statusRecorder remembers the status code for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
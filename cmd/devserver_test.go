@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+)
+
+// startDevServer runs a dev server and points the CLI at it.
+func startDevServer(t *testing.T, policy trek.Policy) *devServer {
+	t.Helper()
+	resetConfigGlobals(t)
+	srv := newDevServer(policy, "dev-token", []string{"dev", "prod"})
+	ts := httptest.NewServer(srv.handler())
+	t.Cleanup(ts.Close)
+	apiEndpoint, apiToken, orgID, env = ts.URL, "dev-token", "org_dev", "dev"
+	return srv
+}
+
+// setCreateFlags sets the flags of 'trek session create' for one test.
+func setCreateFlags(t *testing.T, user string, sessionTTL time.Duration, sessionReason string) {
+	t.Helper()
+	old := []any{userID, requestID, tenantID, route, ttl, level, reason, labels, sessionTemplateName, selectors}
+	t.Cleanup(func() {
+		userID, requestID, tenantID, route = old[0].(string), old[1].(string), old[2].(string), old[3].(string)
+		ttl, level, reason = old[4].(time.Duration), old[5].(string), old[6].(string)
+		labels, sessionTemplateName, selectors = old[7].([]string), old[8].(string), old[9].([]string)
+	})
+	userID, requestID, tenantID, route, selectors = user, "", "", "", nil
+	ttl, level, reason, labels, sessionTemplateName = sessionTTL, "debug", sessionReason, nil, ""
+}
+
+// decodeOutput parses JSON written by a command and resets the buffer.
+func decodeOutput(t *testing.T, buf *bytes.Buffer, v any) {
+	t.Helper()
+	if err := json.Unmarshal(buf.Bytes(), v); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf)
+	}
+	buf.Reset()
+}
+
+func TestDevServerSessionLifecycle(t *testing.T) {
+	startDevServer(t, trek.Policy{MaxTTLSeconds: 3600})
+	buf := captureOutput(t, outputJSON)
+
+	setCreateFlags(t, "u123", 10*time.Minute, "checkout bug")
+	if err := runCreate(sessionCreateCmd, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	var created createdSessionView
+	decodeOutput(t, buf, &created)
+	if created.ID == "" || created.Status != "active" {
+		t.Fatalf("created = %+v, want an active session", created)
+	}
+
+	if err := runGet(sessionGetCmd, []string{created.ID}); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	var got sessionView
+	decodeOutput(t, buf, &got)
+	if got.Selector.UserID != "u123" || got.Reason != "checkout bug" || got.CreatedBy != devUser {
+		t.Errorf("get = %+v, want the created session", got)
+	}
+
+	oldExtendTTL := extendTTL
+	t.Cleanup(func() { extendTTL = oldExtendTTL })
+	extendTTL = 30 * time.Minute
+	if err := runExtend(sessionExtendCmd, []string{created.ID}); err != nil {
+		t.Fatalf("extend: %v", err)
+	}
+	var extended extendedSessionView
+	decodeOutput(t, buf, &extended)
+	if !extended.ExpiresAt.After(created.ExpiresAt) {
+		t.Errorf("extended expiry %v not after %v", extended.ExpiresAt, created.ExpiresAt)
+	}
+
+	revokeYes = true
+	defer func() { revokeYes = false }()
+	if err := runRevoke(sessionRevokeCmd, []string{created.ID}); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	buf.Reset()
+
+	statusFilter = "revoked"
+	defer func() { statusFilter = "" }()
+	if err := runList(sessionListCmd, nil); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var list listView[sessionView]
+	decodeOutput(t, buf, &list)
+	if len(list.Items) != 1 || list.Items[0].ID != created.ID {
+		t.Errorf("revoked sessions = %+v, want %s", list.Items, created.ID)
+	}
+
+	err := runExtend(sessionExtendCmd, []string{created.ID})
+	if err == nil {
+		t.Error("extending a revoked session succeeded")
+	}
+}
+
+func TestDevServerEnforcesPolicy(t *testing.T) {
+	policy := trek.Policy{MaxTTLSeconds: 900, RequireReason: true, AllowedSelectorKeys: []string{"user_id"}}
+
+	tests := []struct {
+		name     string
+		user     string
+		route    string
+		ttl      time.Duration
+		reason   string
+		wantCode string
+	}{
+		{name: "allowed", user: "u1", ttl: 10 * time.Minute, reason: "bug"},
+		{name: "ttl over max", user: "u1", ttl: time.Hour, reason: "bug", wantCode: "policy_max_ttl"},
+		{name: "no reason", user: "u1", ttl: 10 * time.Minute, wantCode: "policy_reason_required"},
+		{name: "selector key", route: "/api/*", ttl: 10 * time.Minute, reason: "bug", wantCode: "policy_selector_key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			startDevServer(t, policy)
+			client, err := getClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Call the API directly: runCreate would stop at its own policy check.
+			_, err = client.CreateSession(t.Context(), trek.CreateSessionRequest{
+				Selector:   trek.Selector{UserID: tt.user, Route: tt.route},
+				Level:      trek.LevelDebug,
+				TTLSeconds: int(tt.ttl.Seconds()),
+				Reason:     tt.reason,
+			})
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("create: %v", err)
+				}
+				return
+			}
+			var apiErr *trek.APIError
+			if got := classifyError(err); got.ExitCode != exitPolicy || !errors.As(err, &apiErr) || apiErr.Code != tt.wantCode {
+				t.Errorf("create error = %v, want policy error %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestDevServerErrors(t *testing.T) {
+	startDevServer(t, trek.Policy{})
+	captureOutput(t, outputJSON)
+
+	err := runGet(sessionGetCmd, []string{"sess_missing"})
+	if got := classifyError(err); got.ExitCode != exitNotFound || got.RequestID == "" {
+		t.Errorf("get missing session = %+v, want not found with a request ID", got)
+	}
+
+	apiToken = "wrong"
+	err = runPoliciesGet(policiesGetCmd, nil)
+	if got := classifyError(err); got.ExitCode != exitAuth {
+		t.Errorf("wrong token exit code = %d (%v), want %d", got.ExitCode, err, exitAuth)
+	}
+}
+
+func TestDevServerTokensAndAudit(t *testing.T) {
+	startDevServer(t, trek.Policy{})
+	buf := captureOutput(t, outputJSON)
+
+	tokensCreateCmd.Flags().Set("name", "ci")
+	defer tokensCreateCmd.Flags().Set("name", "")
+	if err := tokensCreateCmd.RunE(tokensCreateCmd, nil); err != nil {
+		t.Fatalf("tokens create: %v", err)
+	}
+	var created createdTokenView
+	decodeOutput(t, buf, &created)
+	if created.ID == "" || created.Token == "" {
+		t.Fatalf("created token = %+v", created)
+	}
+
+	tokensRevokeCmd.Flags().Set("id", created.ID)
+	defer tokensRevokeCmd.Flags().Set("id", "")
+	if err := tokensRevokeCmd.RunE(tokensRevokeCmd, nil); err != nil {
+		t.Fatalf("tokens revoke: %v", err)
+	}
+	buf.Reset()
+
+	if err := tokensListCmd.RunE(tokensListCmd, nil); err != nil {
+		t.Fatalf("tokens list: %v", err)
+	}
+	var tokens listView[tokenView]
+	decodeOutput(t, buf, &tokens)
+	if len(tokens.Items) != 0 {
+		t.Errorf("tokens after revoke = %+v, want none", tokens.Items)
+	}
+
+	if err := runAuditList(auditListCmd, nil); err != nil {
+		t.Fatalf("audit list: %v", err)
+	}
+	var events listView[auditEventView]
+	decodeOutput(t, buf, &events)
+	if len(events.Items) != 2 || events.Items[0].Action != "token.revoke" || events.Items[1].Action != "token.create" {
+		t.Errorf("audit events = %+v, want token.revoke then token.create", events.Items)
+	}
+
+	if err := runEnvList(envListCmd, nil); err != nil {
+		t.Fatalf("env list: %v", err)
+	}
+	var envs listView[environmentView]
+	decodeOutput(t, buf, &envs)
+	if len(envs.Items) != 2 || envs.Items[1].Name != "prod" {
+		t.Errorf("environments = %+v, want dev and prod", envs.Items)
+	}
+}
+
+func TestDevServerActiveSessionsByService(t *testing.T) {
+	srv := newDevServer(trek.Policy{}, "", nil)
+	srv.sessions = []*devSession{
+		{Session: trek.Session{ID: "sess_checkout", Labels: map[string]string{"service": "checkout"}, ExpiresAt: time.Now().Add(time.Hour)}},
+		{Session: trek.Session{ID: "sess_any", ExpiresAt: time.Now().Add(time.Hour)}},
+	}
+	ts := httptest.NewServer(srv.handler())
+	defer ts.Close()
+
+	tests := map[string]int{"checkout": 2, "cli": 1, "": 2}
+	for service, want := range tests {
+		resp, err := trek.NewClient(ts.URL, "", "org", "dev").GetActiveSessions(t.Context(), service, "")
+		if err != nil {
+			t.Fatalf("GetActiveSessions(%q) error = %v", service, err)
+		}
+		if len(resp.Sessions) != want {
+			t.Errorf("GetActiveSessions(%q) returned %d sessions, want %d", service, len(resp.Sessions), want)
+		}
+	}
+}
+
+// TestDevServerServesSDKClient calls every route through trek.Client, so the
+// dev server's paths, query parameters and envelopes are checked against the
+// SDK's rather than the CLI's view of them.
+func TestDevServerServesSDKClient(t *testing.T) {
+	srv := newDevServer(trek.Policy{MaxTTLSeconds: 3600}, "dev-token", []string{"dev", "prod"})
+	ts := httptest.NewServer(srv.handler())
+	defer ts.Close()
+	client := trek.NewClient(ts.URL, "dev-token", "org_dev", "dev")
+	ctx := t.Context()
+
+	created, err := client.CreateSession(ctx, trek.CreateSessionRequest{
+		Selector:   trek.Selector{UserID: "u1"},
+		Level:      trek.LevelDebug,
+		TTLSeconds: 600,
+		Labels:     map[string]string{"service": "checkout"},
+	})
+	if err != nil || created.ID == "" || created.Status != "active" {
+		t.Fatalf("CreateSession() = %+v, %v", created, err)
+	}
+	if got, err := client.GetSession(ctx, created.ID); err != nil || got.Selector.UserID != "u1" {
+		t.Errorf("GetSession() = %+v, %v", got, err)
+	}
+	if got, err := client.ListSessions(ctx, "active"); err != nil || len(got) != 1 {
+		t.Errorf("ListSessions(active) = %+v, %v", got, err)
+	}
+	if got, err := client.ExtendSession(ctx, created.ID, 600); err != nil || got.ID != created.ID || !got.ExpiresAt.After(created.ExpiresAt) {
+		t.Errorf("ExtendSession() = %+v, %v", got, err)
+	}
+
+	active, err := client.GetActiveSessions(ctx, "checkout", "")
+	if err != nil || len(active.Sessions) != 1 || active.ETag == "" || active.NotModified {
+		t.Fatalf("GetActiveSessions() = %+v, %v", active, err)
+	}
+	if again, err := client.GetActiveSessions(ctx, "checkout", active.ETag); err != nil || !again.NotModified {
+		t.Errorf("GetActiveSessions() with the current ETag = %+v, %v, want not modified", again, err)
+	}
+	if other, err := client.GetActiveSessions(ctx, "billing", ""); err != nil || len(other.Sessions) != 0 {
+		t.Errorf("GetActiveSessions(billing) = %+v, %v, want no sessions", other, err)
+	}
+
+	if policy, err := client.GetPolicy(ctx); err != nil || policy.MaxTTLSeconds != 3600 {
+		t.Errorf("GetPolicy() = %+v, %v", policy, err)
+	}
+	token, err := client.CreateToken(ctx, "ci")
+	if err != nil || token.ID == "" || token.Token == "" {
+		t.Fatalf("CreateToken() = %+v, %v", token, err)
+	}
+	if got, err := client.ListTokens(ctx); err != nil || len(got) != 1 || got[0].Name != "ci" {
+		t.Errorf("ListTokens() = %+v, %v", got, err)
+	}
+	if err := client.RevokeToken(ctx, token.ID); err != nil {
+		t.Errorf("RevokeToken() error = %v", err)
+	}
+	if got, err := client.ListEnvironments(ctx); err != nil || len(got) != 2 {
+		t.Errorf("ListEnvironments() = %+v, %v", got, err)
+	}
+	if err := client.RevokeSession(ctx, created.ID); err != nil {
+		t.Errorf("RevokeSession() error = %v", err)
+	}
+	if got, err := client.ListSessions(ctx, "revoked"); err != nil || len(got) != 1 {
+		t.Errorf("ListSessions(revoked) = %+v, %v", got, err)
+	}
+	events, err := client.ListAuditEvents(ctx, "")
+	if err != nil || len(events.Events) == 0 {
+		t.Errorf("ListAuditEvents() = %+v, %v", events, err)
+	}
+}
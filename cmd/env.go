@@ -1,14 +1,11 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 var envCmd = &cobra.Command{
@@ -49,7 +46,7 @@ func runEnvList(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := withTimeout(cmd.Context())
 	defer cancel()
 
 	envs, err := client.ListEnvironments(ctx)
@@ -57,71 +54,63 @@ func runEnvList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to list environments: %w", err)
 	}
 
-	if len(envs) == 0 {
-		fmt.Println("No environments found")
-		return nil
+	views := make([]environmentView, len(envs))
+	for i, e := range envs {
+		views[i] = environmentView{ID: e.ID, Name: e.Name, CreatedAt: e.CreatedAt, Current: e.Name == env}
 	}
+	return printList(views, environmentColumns, "No environments found")
+}
 
-	fmt.Printf("%-20s %-30s %s\n", "NAME", "ID", "CREATED")
-	fmt.Println("----------------------------------------------------------------------")
+type environmentView struct {
+	ID        string    `json:"id" yaml:"id"`
+	Name      string    `json:"name" yaml:"name"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	Current   bool      `json:"current" yaml:"current"`
+}
 
-	for _, e := range envs {
-		marker := ""
-		if e.Name == env {
-			marker = " (current)"
-		}
-		fmt.Printf("%-20s %-30s %s%s\n",
-			e.Name,
-			e.ID,
-			e.CreatedAt.Format("2006-01-02 15:04:05"),
-			marker,
-		)
-	}
+func (v environmentView) id() string { return v.ID }
 
-	return nil
+var environmentColumns = []column[environmentView]{
+	{Header: "NAME", Value: func(e environmentView) string {
+		if e.Current {
+			return e.Name + " (current)"
+		}
+		return e.Name
+	}},
+	{Header: "ID", Value: func(e environmentView) string { return e.ID }},
+	{Header: "CREATED", Value: func(e environmentView) string { return e.CreatedAt.Format("2006-01-02 15:04:05") }},
 }
 
 func runEnvSwitch(cmd *cobra.Command, args []string) error {
 	targetEnv := args[0]
 
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	configPath := filepath.Join(home, ".trek", "config.yaml")
-
-	var cfg map[string]interface{}
-	data, err := os.ReadFile(configPath)
+	doc, err := loadConfigDocument(cfgFile)
 	if err != nil {
-		if os.IsNotExist(err) {
-			cfg = make(map[string]interface{})
-		} else {
-			return fmt.Errorf("failed to read config: %w", err)
-		}
-	} else {
-		if err := yaml.Unmarshal(data, &cfg); err != nil {
-			return fmt.Errorf("failed to parse config: %w", err)
-		}
+		return err
 	}
 
-	cfg["env"] = targetEnv
-
-	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+	// The active context's env takes precedence over the top-level key, so
+	// switch it there.
+	key := "env"
+	if activeContext != nil {
+		key = "contexts." + activeContext.Name + ".env"
 	}
-
-	newData, err := yaml.Marshal(cfg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+	if err := doc.set(key, targetEnv); err != nil {
+		return err
 	}
-
-	if err := os.WriteFile(configPath, newData, 0600); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
+	if err := doc.save(); err != nil {
+		return err
 	}
 
 	env = targetEnv
 
+	if activeContext != nil {
+		fmt.Printf("Switched to environment: %s (context %s)\n", targetEnv, activeContext.Name)
+		return nil
+	}
 	fmt.Printf("Switched to environment: %s\n", targetEnv)
+	if project != nil && project.Env != "" && project.Env != targetEnv {
+		fmt.Fprintf(os.Stderr, "Note: %s sets env %s, which takes precedence in this project\n", projectFile, project.Env)
+	}
 	return nil
 }
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/bold-minds/trek-go"
+)
+
+// Exit codes, documented in the README. Scripts rely on these, so never
+// renumber them.
+const (
+	exitOK          = 0
+	exitError       = 1   // anything not covered below
+	exitUsage       = 2   // invalid flags, arguments or configuration
+	exitAuth        = 3   // not authenticated, or not allowed
+	exitNotFound    = 4   // the session, token or other resource does not exist
+	exitPolicy      = 5   // the org's policy rejected the request
+	exitNetwork     = 6   // the API could not be reached or timed out
+	exitUnavailable = 7   // the API failed or is rate limiting
+	exitInterrupted = 130 // cancelled by Ctrl+C or SIGTERM, as shells report SIGINT
+)
+
+// Error codes in the JSON error object, one per kind of failure.
+const (
+	codeError           = "error"
+	codeUsage           = "usage"
+	codeUnauthenticated = "unauthenticated"
+	codeForbidden       = "forbidden"
+	codeNotFound        = "not_found"
+	codePolicy          = "policy_rejected"
+	codeNetwork         = "network"
+	codeTimeout         = "timeout"
+	codeRateLimited     = "rate_limited"
+	codeServer          = "server_error"
+	codeCanceled        = "canceled"
+)
+
+// cliError is an error with the code and exit status reported for it.
+// Wrapping it with fmt.Errorf keeps both.
+type cliError struct {
+	Code       string
+	ExitCode   int
+	HTTPStatus int
+	RequestID  string
+	Err        error
+}
+
+func (e *cliError) Error() string { return e.Err.Error() }
+func (e *cliError) Unwrap() error { return e.Err }
+
+// usageError reports invalid flags, arguments or configuration.
+func usageError(format string, args ...any) error {
+	return &cliError{Code: codeUsage, ExitCode: exitUsage, Err: fmt.Errorf(format, args...)}
+}
+
+// authError reports missing or unusable credentials.
+func authError(format string, args ...any) error {
+	return &cliError{Code: codeUnauthenticated, ExitCode: exitAuth, Err: fmt.Errorf(format, args...)}
+}
+
+// classifyError works out the code and exit status for err. The message is
+// always err's full text, so context added by wrapping is kept.
+func classifyError(err error) *cliError {
+	var cliErr *cliError
+	var apiErr *trek.APIError
+	var netErr net.Error
+
+	var c cliError
+	switch {
+	case errors.As(err, &cliErr):
+		c = *cliErr
+	case errors.As(err, &apiErr):
+		c = apiErrorKind(apiErr)
+	case errors.Is(err, context.Canceled):
+		c = cliError{Code: codeCanceled, ExitCode: exitInterrupted}
+	case errors.Is(err, context.DeadlineExceeded):
+		c = cliError{Code: codeTimeout, ExitCode: exitNetwork}
+	case errors.As(err, &netErr):
+		c = cliError{Code: codeNetwork, ExitCode: exitNetwork}
+	case errors.Is(err, errCredentialsExpired) || errors.Is(err, errRefreshRevoked):
+		c = cliError{Code: codeUnauthenticated, ExitCode: exitAuth}
+	default:
+		c = cliError{Code: codeError, ExitCode: exitError}
+	}
+	c.Err = err
+	return &c
+}
+
+// apiErrorKind maps an API error to a code and exit status. Policy errors
+// are recognized by their API code as well as by status, since the API
+// rejects some policy violations with 400 or 403.
+func apiErrorKind(e *trek.APIError) cliError {
+	c := cliError{HTTPStatus: e.StatusCode, RequestID: e.RequestID}
+	switch {
+	case strings.HasPrefix(e.Code, "policy"):
+		c.Code, c.ExitCode = codePolicy, exitPolicy
+	case e.StatusCode == http.StatusUnauthorized:
+		c.Code, c.ExitCode = codeUnauthenticated, exitAuth
+	case e.StatusCode == http.StatusForbidden:
+		c.Code, c.ExitCode = codeForbidden, exitAuth
+	case e.StatusCode == http.StatusNotFound:
+		c.Code, c.ExitCode = codeNotFound, exitNotFound
+	case e.StatusCode == http.StatusConflict || e.StatusCode == http.StatusUnprocessableEntity:
+		c.Code, c.ExitCode = codePolicy, exitPolicy
+	case e.StatusCode == http.StatusTooManyRequests:
+		c.Code, c.ExitCode = codeRateLimited, exitUnavailable
+	case e.StatusCode >= 500:
+		c.Code, c.ExitCode = codeServer, exitUnavailable
+	case e.StatusCode >= 400:
+		c.Code, c.ExitCode = codeUsage, exitUsage
+	default:
+		c.Code, c.ExitCode = codeError, exitError
+	}
+	return c
+}
+
+// errorView is the error object written to stderr for machine-readable
+// output formats.
+type errorView struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+	ExitCode   int    `json:"exit_code"`
+}
+
+// printError writes err to w: as {"error": {...}} when the output format is
+// for programs, otherwise as an "Error:" line.
+func printError(w io.Writer, err *cliError) {
+	if format, _, ferr := outputFormat(); ferr == nil && format != outputTable && format != outputWide {
+		json.NewEncoder(w).Encode(struct {
+			Error errorView `json:"error"`
+		}{errorView{
+			Code:       err.Code,
+			Message:    err.Error(),
+			HTTPStatus: err.HTTPStatus,
+			RequestID:  err.RequestID,
+			ExitCode:   err.ExitCode,
+		}})
+		return
+	}
+
+	fmt.Fprintf(w, "Error: %v\n", err)
+	if err.RequestID != "" {
+		fmt.Fprintf(w, "  Request ID: %s (HTTP %d)\n", err.RequestID, err.HTTPStatus)
+	}
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bold-minds/trek-go"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode string
+		wantExit int
+	}{
+		{"plain", errors.New("boom"), codeError, exitError},
+		{"usage", usageError("invalid --sort-by %q", "name"), codeUsage, exitUsage},
+		{"wrapped auth", fmt.Errorf("failed: %w", authError("API token required")), codeUnauthenticated, exitAuth},
+		{"expired login", fmt.Errorf("API token required: %w", errCredentialsExpired), codeUnauthenticated, exitAuth},
+		{"401", &trek.APIError{StatusCode: 401}, codeUnauthenticated, exitAuth},
+		{"403", &trek.APIError{StatusCode: 403}, codeForbidden, exitAuth},
+		{"404", fmt.Errorf("failed to get session: %w", &trek.APIError{StatusCode: 404}), codeNotFound, exitNotFound},
+		{"422", &trek.APIError{StatusCode: 422}, codePolicy, exitPolicy},
+		{"policy code", &trek.APIError{StatusCode: 400, Code: "policy_ttl_exceeded"}, codePolicy, exitPolicy},
+		{"400", &trek.APIError{StatusCode: 400, Code: "invalid_request"}, codeUsage, exitUsage},
+		{"429", &trek.APIError{StatusCode: 429}, codeRateLimited, exitUnavailable},
+		{"503", &trek.APIError{StatusCode: 503}, codeServer, exitUnavailable},
+		{"canceled", fmt.Errorf("failed to create session: %w", context.Canceled), codeCanceled, exitInterrupted},
+		{"timeout", fmt.Errorf("failed to list sessions: %w", context.DeadlineExceeded), codeTimeout, exitNetwork},
+		{"network", fmt.Errorf("failed to list sessions: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), codeNetwork, exitNetwork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyError(tt.err)
+			if got.Code != tt.wantCode || got.ExitCode != tt.wantExit {
+				t.Errorf("classifyError() = %s/%d, want %s/%d", got.Code, got.ExitCode, tt.wantCode, tt.wantExit)
+			}
+			if got.Error() != tt.err.Error() {
+				t.Errorf("message = %q, want %q", got.Error(), tt.err.Error())
+			}
+		})
+	}
+}
+
+func TestPrintErrorJSON(t *testing.T) {
+	captureOutput(t, outputJSON)
+	apiErr := &trek.APIError{StatusCode: 404, Code: "not_found", Message: "session not found", RequestID: "req_123"}
+
+	var buf bytes.Buffer
+	printError(&buf, classifyError(fmt.Errorf("failed to get session: %w", apiErr)))
+
+	var got struct {
+		Error errorView `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("error output is not JSON: %v\n%s", err, buf.String())
+	}
+	want := errorView{
+		Code:       codeNotFound,
+		Message:    "failed to get session: session not found",
+		HTTPStatus: 404,
+		RequestID:  "req_123",
+		ExitCode:   exitNotFound,
+	}
+	if got.Error != want {
+		t.Errorf("error = %+v, want %+v", got.Error, want)
+	}
+}
+
+func TestPrintErrorHuman(t *testing.T) {
+	captureOutput(t, outputTable)
+
+	var buf bytes.Buffer
+	printError(&buf, classifyError(&trek.APIError{StatusCode: 503, Message: "unavailable", RequestID: "req_9"}))
+
+	want := "Error: unavailable\n  Request ID: req_9 (HTTP 503)\n"
+	if buf.String() != want {
+		t.Errorf("printError() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestFlagErrorsAreUsageErrors(t *testing.T) {
+	err := sessionListCmd.FlagErrorFunc()(sessionListCmd, errors.New("unknown flag: --nope"))
+	if got := classifyError(err); got.ExitCode != exitUsage {
+		t.Errorf("flag error exit code = %d, want %d", got.ExitCode, exitUsage)
+	}
+}
+
+// TestClassifySDKErrors sends real requests through the SDK client, so it
+// fails if trek-go stops returning *trek.APIError with the status, code and
+// request ID the exit codes are mapped from.
+func TestClassifySDKErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		apiCode  string
+		wantCode string
+		wantExit int
+	}{
+		{http.StatusUnauthorized, "unauthenticated", codeUnauthenticated, exitAuth},
+		{http.StatusForbidden, "forbidden", codeForbidden, exitAuth},
+		{http.StatusNotFound, "not_found", codeNotFound, exitNotFound},
+		{http.StatusBadRequest, "policy_max_ttl", codePolicy, exitPolicy},
+		{http.StatusUnprocessableEntity, "invalid_request", codePolicy, exitPolicy},
+		{http.StatusBadRequest, "invalid_request", codeUsage, exitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d %s", tt.status, tt.apiCode), func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("X-Request-Id", "req_123")
+				w.WriteHeader(tt.status)
+				fmt.Fprintf(w, `{"code": %q, "message": "rejected", "request_id": "req_123"}`, tt.apiCode)
+			}))
+			defer ts.Close()
+
+			_, err := trek.NewClient(ts.URL, "tok", "org", "dev").GetSession(t.Context(), "sess_1")
+			got := classifyError(fmt.Errorf("failed to get session: %w", err))
+			if got.Code != tt.wantCode || got.ExitCode != tt.wantExit {
+				t.Errorf("classifyError() = %s/%d, want %s/%d (err %v)", got.Code, got.ExitCode, tt.wantCode, tt.wantExit, err)
+			}
+			if got.HTTPStatus != tt.status || got.RequestID != "req_123" {
+				t.Errorf("HTTP status %d, request ID %q; want %d and req_123", got.HTTPStatus, got.RequestID, tt.status)
+			}
+		})
+	}
+}
+
+func TestGetClientErrorsAreTyped(t *testing.T) {
+	resetConfigGlobals(t)
+
+	_, err := getClient()
+	if got := classifyError(err); got.ExitCode != exitUsage {
+		t.Errorf("missing endpoint exit code = %d, want %d", got.ExitCode, exitUsage)
+	}
+
+	apiEndpoint = "http://localhost"
+	t.Setenv("HOME", t.TempDir())
+	_, err = getClient()
+	if got := classifyError(err); got.ExitCode != exitAuth {
+		t.Errorf("missing token exit code = %d (%v), want %d", got.ExitCode, err, exitAuth)
+	}
+}
@@ -1,26 +1,34 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
+	"io"
 	"time"
 
+	"github.com/bold-minds/trek-go"
 	"github.com/spf13/cobra"
 )
 
 var (
-	extendSessionID string
-	extendTTL       time.Duration
+	extendSessionID     string
+	extendTTL           time.Duration
+	extendMaxPerRequest int
+	extendMaxPerSession int
 )
 
 var sessionExtendCmd = &cobra.Command{
 	Use:   "extend <session_id>",
-	Short: "Extend session TTL",
-	Long: `Extend the TTL of an active debug session.
+	Short: "Extend session TTL or change its caps",
+	Long: `Extend the TTL of an active debug session, or raise or lower its
+debug-event caps.
+
+With only cap flags, the TTL is left alone; add --ttl to change both.
 
 Examples:
   trek session extend sess_abc123 --ttl 30m
-  trek session extend sess_abc123 --ttl 1h`,
+  trek session extend sess_abc123 --ttl 1h
+  trek session extend sess_abc123 --max-events-per-request 200
+  trek session extend sess_abc123 --ttl 30m --max-events-per-session 10000`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runExtend,
 }
@@ -30,6 +38,8 @@ func init() {
 
 	sessionExtendCmd.Flags().StringVar(&extendSessionID, "session", "", "Session ID (alternative to positional arg)")
 	sessionExtendCmd.Flags().DurationVar(&extendTTL, "ttl", 15*time.Minute, "Additional TTL to add (e.g., 15m, 1h)")
+	sessionExtendCmd.Flags().IntVar(&extendMaxPerRequest, "max-events-per-request", 0, "New cap on debug events per request")
+	sessionExtendCmd.Flags().IntVar(&extendMaxPerSession, "max-events-per-session", 0, "New cap on debug events per session")
 }
 
 func runExtend(cmd *cobra.Command, args []string) error {
@@ -39,30 +49,101 @@ func runExtend(cmd *cobra.Command, args []string) error {
 		sessionID = args[0]
 	}
 	if sessionID == "" {
-		return fmt.Errorf("session ID required\n  Usage: trek session extend <session_id> --ttl <duration>\n  Example: trek session extend sess_abc123 --ttl 30m")
+		return usageError("session ID required\n  Usage: trek session extend <session_id> --ttl <duration>\n  Example: trek session extend sess_abc123 --ttl 30m")
 	}
 
+	caps := capsFromFlags(extendMaxPerRequest, extendMaxPerSession)
+	extend := caps == nil || cmd.Flags().Changed("ttl")
+
 	client, err := getClient()
 	if err != nil {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := withTimeout(cmd.Context())
 	defer cancel()
 
-	resp, err := client.ExtendSession(ctx, sessionID, int(extendTTL.Seconds()))
+	var local []policyViolation
+	if extend && extendTTL <= 0 {
+		local = append(local, policyViolation{Problem: "ttl must be positive", Fix: "use --ttl 15m", Usage: true})
+	}
+	if caps == nil {
+		err = violationsError(local)
+	} else {
+		// Both changes are checked before either is made, so a rejected
+		// TTL doesn't leave the caps changed.
+		local = append(local, negativeCapsViolations(*caps)...)
+		err = preflight(ctx, client, false, local, func(policy trek.Policy) []policyViolation {
+			return checkExtendRequest(extend, int(extendTTL.Seconds()), caps, policy)
+		})
+	}
 	if err != nil {
-		return fmt.Errorf("failed to extend session: %w", err)
+		return err
+	}
+
+	view := extendedSessionView{ID: sessionID}
+	if caps != nil {
+		sess, err := client.UpdateSessionCaps(ctx, sessionID, *caps)
+		if err != nil {
+			return fmt.Errorf("failed to update session caps: %w", err)
+		}
+		updated := capsView(sess.Caps)
+		view.ExpiresAt, view.Caps = sess.ExpiresAt, &updated
+	}
+	if extend {
+		resp, err := client.ExtendSession(ctx, sessionID, int(extendTTL.Seconds()))
+		if err != nil && caps != nil {
+			// The caps change went through; report it along with the failure.
+			if perr := printItem(view, printExtendedSession); perr != nil {
+				return perr
+			}
+			return fmt.Errorf("session caps were updated, but extending the session failed: %w", err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extend session: %w", err)
+		}
+		view.ExpiresAt, view.Extended = resp.ExpiresAt, true
 	}
 
-	if quietMode {
-		fmt.Println(resp.ID)
-		return nil
+	return printItem(view, printExtendedSession)
+}
+
+// checkExtendRequest checks an extend by ttlSeconds, if extend is set, and
+// a caps change, if caps is not nil, against policy.
+func checkExtendRequest(extend bool, ttlSeconds int, caps *trek.Caps, policy trek.Policy) []policyViolation {
+	var violations []policyViolation
+	if extend && policy.MaxTTLSeconds > 0 && ttlSeconds > policy.MaxTTLSeconds {
+		violations = append(violations, policyViolation{
+			Problem: fmt.Sprintf("max TTL is %s", formatDuration(policy.MaxTTLSeconds)),
+			Fix:     "use --ttl " + ttlFlagValue(policy.MaxTTLSeconds),
+		})
 	}
+	if caps != nil {
+		violations = append(violations, capsViolations(*caps, policy.DefaultCaps)...)
+	}
+	return violations
+}
 
-	fmt.Printf("Session extended successfully\n")
-	fmt.Printf("  ID:         %s\n", resp.ID)
-	fmt.Printf("  New Expiry: %s\n", resp.ExpiresAt.Format(time.RFC3339))
+func printExtendedSession(w io.Writer, v extendedSessionView) {
+	if v.Extended {
+		fmt.Fprintf(w, "Session extended successfully\n")
+	} else {
+		fmt.Fprintf(w, "Session caps updated successfully\n")
+	}
+	fmt.Fprintf(w, "  ID:         %s\n", v.ID)
+	fmt.Fprintf(w, "  New Expiry: %s\n", v.ExpiresAt.Format(time.RFC3339))
+	if v.Caps != nil {
+		fmt.Fprintf(w, "  Max Events/Request: %s\n", formatCap(v.Caps.MaxDebugEventsPerRequest))
+		fmt.Fprintf(w, "  Max Events/Session: %s\n", formatCap(v.Caps.MaxDebugEventsPerSession))
+	}
+}
 
-	return nil
+type extendedSessionView struct {
+	ID        string    `json:"id" yaml:"id"`
+	ExpiresAt time.Time `json:"expires_at" yaml:"expires_at"`
+	Extended  bool      `json:"extended" yaml:"extended"`
+	// Caps is set when the caps were changed.
+	Caps *capsView `json:"caps,omitempty" yaml:"caps,omitempty"`
 }
+
+func (v extendedSessionView) id() string { return v.ID }
@@ -1,13 +1,14 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"slices"
 	"time"
 
+	"github.com/bold-minds/trek-go"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 var getSessionID string
@@ -37,7 +38,7 @@ func runGet(cmd *cobra.Command, args []string) error {
 		sessionID = args[0]
 	}
 	if sessionID == "" {
-		return fmt.Errorf("session ID required\n  Usage: trek session get <session_id>\n  Example: trek session get sess_abc123")
+		return usageError("session ID required\n  Usage: trek session get <session_id>\n  Example: trek session get sess_abc123")
 	}
 
 	client, err := getClient()
@@ -45,7 +46,7 @@ func runGet(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := withTimeout(cmd.Context())
 	defer cancel()
 
 	session, err := client.GetSession(ctx, sessionID)
@@ -53,56 +54,30 @@ func runGet(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 
-	// Handle output format
-	switch outputFmt {
-	case "json":
-		data, err := json.MarshalIndent(session, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal session: %w", err)
-		}
-		fmt.Println(string(data))
-	case "yaml":
-		data, err := yaml.Marshal(session)
-		if err != nil {
-			return fmt.Errorf("failed to marshal session: %w", err)
-		}
-		fmt.Print(string(data))
-	default:
-		// Table/human-readable format
-		if quietMode {
-			fmt.Println(session.ID)
-			return nil
-		}
+	return printItem(newSessionView(*session), writeSessionDetails)
+}
 
-		selectorStr := formatSelector(session.Selector)
-		status := "active"
-		if time.Now().After(session.ExpiresAt) {
-			status = "expired"
+func writeSessionDetails(w io.Writer, s sessionView) {
+	fmt.Fprintf(w, "Session Details\n")
+	fmt.Fprintln(w, "----------------------------------------")
+	fmt.Fprintf(w, "  ID:         %s\n", s.ID)
+	fmt.Fprintf(w, "  Status:     %s\n", paint(sessionStatusColor(s.Status, s.ExpiresAt), s.Status))
+	fmt.Fprintf(w, "  Level:      %s\n", paint(levelColor(s.Level), s.Level))
+	fmt.Fprintf(w, "  Selector:   %s\n", formatSelector(trek.Selector(s.Selector)))
+	fmt.Fprintf(w, "  Expires:    %s\n", s.ExpiresAt.Format(time.RFC3339))
+	if len(s.Labels) > 0 {
+		fmt.Fprintf(w, "  Labels:\n")
+		for _, k := range slices.Sorted(maps.Keys(s.Labels)) {
+			fmt.Fprintf(w, "    %s: %s\n", k, s.Labels[k])
 		}
-
-		fmt.Printf("Session Details\n")
-		fmt.Println("----------------------------------------")
-		fmt.Printf("  ID:         %s\n", session.ID)
-		fmt.Printf("  Status:     %s\n", status)
-		fmt.Printf("  Level:      %s\n", session.Level)
-		fmt.Printf("  Selector:   %s\n", selectorStr)
-		fmt.Printf("  Expires:    %s\n", session.ExpiresAt.Format(time.RFC3339))
-		if len(session.Labels) > 0 {
-			fmt.Printf("  Labels:\n")
-			for k, v := range session.Labels {
-				fmt.Printf("    %s: %s\n", k, v)
-			}
+	}
+	if s.Caps.MaxDebugEventsPerRequest > 0 || s.Caps.MaxDebugEventsPerSession > 0 {
+		fmt.Fprintf(w, "  Caps:\n")
+		if s.Caps.MaxDebugEventsPerRequest > 0 {
+			fmt.Fprintf(w, "    Max Events/Request: %d\n", s.Caps.MaxDebugEventsPerRequest)
 		}
-		if session.Caps.MaxDebugEventsPerRequest > 0 || session.Caps.MaxDebugEventsPerSession > 0 {
-			fmt.Printf("  Caps:\n")
-			if session.Caps.MaxDebugEventsPerRequest > 0 {
-				fmt.Printf("    Max Events/Request: %d\n", session.Caps.MaxDebugEventsPerRequest)
-			}
-			if session.Caps.MaxDebugEventsPerSession > 0 {
-				fmt.Printf("    Max Events/Session: %d\n", session.Caps.MaxDebugEventsPerSession)
-			}
+		if s.Caps.MaxDebugEventsPerSession > 0 {
+			fmt.Fprintf(w, "    Max Events/Session: %d\n", s.Caps.MaxDebugEventsPerSession)
 		}
 	}
-
-	return nil
 }
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+)
+
+// sensitiveFields are JSON body fields that are never logged.
+var sensitiveFields = map[string]bool{
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"client_secret": true,
+	"password":      true,
+	"secret":        true,
+}
+
+// sensitiveHeaders are headers whose values are never logged.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// traceTransport logs every HTTP request to w: one line per request with
+// -v, plus headers and bodies with -vv. Credentials are masked either way.
+// A client that retries sends each attempt through the transport, so each
+// gets its own line.
+type traceTransport struct {
+	next   http.RoundTripper
+	w      io.Writer
+	bodies bool
+}
+
+func newTraceTransport(next http.RoundTripper, w io.Writer, bodies bool) *traceTransport {
+	return &traceTransport{next: next, w: w, bodies: bodies}
+}
+
+// traceOutput is where -v logs API calls.
+var traceOutput io.Writer = os.Stderr
+
+// traceOption plugs a traceTransport into the SDK client. The SDK keeps its
+// own transport and retries; the trace transport only wraps it.
+func traceOption(bodies bool) trek.Option {
+	return trek.WithTransportWrapper(func(next http.RoundTripper) http.RoundTripper {
+		return newTraceTransport(next, traceOutput, bodies)
+	})
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target := redactURL(req.URL)
+
+	if t.bodies {
+		var body []byte
+		if req.Body != nil {
+			var err error
+			body, err = io.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		t.logf("> %s %s\n", req.Method, target)
+		t.logHeaders(">", req.Header)
+		t.logBody(">", body)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	line := fmt.Sprintf("%s %s", req.Method, target)
+	if err != nil {
+		t.logf("%s: error after %s: %v\n", line, elapsed, err)
+		return nil, err
+	}
+
+	line += fmt.Sprintf(": %s in %s", resp.Status, elapsed)
+	if id := resp.Header.Get("X-Request-Id"); id != "" {
+		line += ", request ID " + id
+	}
+	t.logf("%s\n", line)
+
+	if t.bodies {
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if readErr != nil {
+			return nil, readErr
+		}
+		t.logHeaders("<", resp.Header)
+		t.logBody("<", body)
+	}
+	return resp, nil
+}
+
+func (t *traceTransport) logf(format string, args ...any) {
+	fmt.Fprintf(t.w, format, args...)
+}
+
+func (t *traceTransport) logHeaders(prefix string, h http.Header) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := strings.Join(h[name], ", ")
+		if sensitiveHeaders[name] {
+			value = redactHeader(value)
+		}
+		t.logf("%s %s: %s\n", prefix, name, value)
+	}
+}
+
+func (t *traceTransport) logBody(prefix string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	for _, line := range strings.Split(redactBody(body), "\n") {
+		t.logf("%s %s\n", prefix, line)
+	}
+}
+
+// redactURL masks sensitive query parameters.
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	masked := false
+	for key := range query {
+		if sensitiveFields[strings.ToLower(key)] {
+			query.Set(key, "****")
+			masked = true
+		}
+	}
+	if !masked {
+		return u.String()
+	}
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// redactHeader keeps the auth scheme so "Bearer" vs "Basic" is still
+// visible, and masks the credential.
+func redactHeader(value string) string {
+	if scheme, _, ok := strings.Cut(value, " "); ok {
+		return scheme + " ****"
+	}
+	return "****"
+}
+
+// redactBody masks sensitive fields anywhere in a JSON body, or in a form
+// body such as a token refresh. Other bodies are logged as they are.
+func redactBody(body []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return redactForm(body)
+	}
+	out, err := json.MarshalIndent(redactValue(v), "", "  ")
+	if err != nil {
+		return string(body)
+	}
+	return string(out)
+}
+
+func redactForm(body []byte) string {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return string(body)
+	}
+	masked := false
+	for key := range form {
+		if sensitiveFields[strings.ToLower(key)] {
+			form.Set(key, "****")
+			masked = true
+		}
+	}
+	if !masked {
+		return string(body)
+	}
+	return form.Encode()
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if sensitiveFields[strings.ToLower(k)] {
+				v[k] = "****"
+			} else {
+				v[k] = redactValue(field)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return v
+}
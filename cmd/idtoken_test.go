@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSigner issues ID tokens and serves the matching JWKS.
+type testSigner struct {
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	jwks   *httptest.Server
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+
+	s := &testSigner{rsaKey: rsaKey, ecKey: ecKey}
+	b64 := base64.RawURLEncoding.EncodeToString
+	s.jwks = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []jsonWebKey{
+				{
+					Kty: "RSA",
+					Kid: "rsa-1",
+					N:   b64(rsaKey.N.Bytes()),
+					E:   b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+				},
+				{
+					Kty: "EC",
+					Kid: "ec-1",
+					Crv: "P-256",
+					X:   b64(ecKey.X.FillBytes(make([]byte, 32))),
+					Y:   b64(ecKey.Y.FillBytes(make([]byte, 32))),
+				},
+			},
+		})
+	}))
+	t.Cleanup(s.jwks.Close)
+	return s
+}
+
+func (s *testSigner) provider() *oidcProvider {
+	return &oidcProvider{Issuer: "https://idp.example.com", JWKSURI: s.jwks.URL}
+}
+
+func (s *testSigner) sign(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	b64 := base64.RawURLEncoding.EncodeToString
+	header, _ := json.Marshal(jwtHeader{Alg: alg, Kid: kid})
+	payload, _ := json.Marshal(claims)
+	input := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch alg {
+	case "RS256":
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, s.rsaKey, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+	case "ES256":
+		r, ss, err := ecdsa.Sign(rand.Reader, s.ecKey, digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), ss.FillBytes(make([]byte, 32))...)
+	}
+	return input + "." + b64(sig)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"iss":    "https://idp.example.com",
+		"sub":    "user_123",
+		"aud":    "client-123",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"email":  "dev@example.com",
+		"name":   "Dev Eloper",
+		"org_id": "org_abc",
+	}
+}
+
+func TestValidateIDToken_RS256(t *testing.T) {
+	s := newTestSigner(t)
+	token := s.sign(t, "RS256", "rsa-1", validClaims())
+
+	claims, err := validateIDToken(context.Background(), s.provider(), "client-123", token)
+	if err != nil {
+		t.Fatalf("validateIDToken() error: %v", err)
+	}
+	if claims.Subject != "user_123" {
+		t.Errorf("Subject = %q, want user_123", claims.Subject)
+	}
+	if claims.Email != "dev@example.com" {
+		t.Errorf("Email = %q, want dev@example.com", claims.Email)
+	}
+	if claims.organization() != "org_abc" {
+		t.Errorf("organization() = %q, want org_abc", claims.organization())
+	}
+}
+
+func TestValidateIDToken_ES256AudienceArray(t *testing.T) {
+	s := newTestSigner(t)
+	c := validClaims()
+	c["aud"] = []string{"other", "client-123"}
+	token := s.sign(t, "ES256", "ec-1", c)
+
+	if _, err := validateIDToken(context.Background(), s.provider(), "client-123", token); err != nil {
+		t.Fatalf("validateIDToken() error: %v", err)
+	}
+}
+
+func TestValidateIDToken_Rejects(t *testing.T) {
+	s := newTestSigner(t)
+
+	tests := []struct {
+		name    string
+		edit    func(c map[string]any)
+		kid     string
+		tamper  bool
+		wantErr string
+	}{
+		{
+			name:    "wrong issuer",
+			edit:    func(c map[string]any) { c["iss"] = "https://evil.example.com" },
+			wantErr: "issuer",
+		},
+		{
+			name:    "wrong audience",
+			edit:    func(c map[string]any) { c["aud"] = "someone-else" },
+			wantErr: "audience",
+		},
+		{
+			name:    "expired",
+			edit:    func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+			wantErr: "expired",
+		},
+		{
+			name:    "unknown key",
+			kid:     "rotated-away",
+			wantErr: "no signing key",
+		},
+		{
+			name:    "tampered payload",
+			tamper:  true,
+			wantErr: "signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validClaims()
+			if tt.edit != nil {
+				tt.edit(c)
+			}
+			kid := tt.kid
+			if kid == "" {
+				kid = "rsa-1"
+			}
+			token := s.sign(t, "RS256", kid, c)
+			if tt.tamper {
+				forged := validClaims()
+				forged["sub"] = "admin"
+				other := s.sign(t, "RS256", kid, forged)
+				token = token[:len(token)-len(lastSegment(token))] + lastSegment(other)
+			}
+
+			_, err := validateIDToken(context.Background(), s.provider(), "client-123", token)
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func lastSegment(token string) string {
+	return token[strings.LastIndex(token, ".")+1:]
+}
+
+func TestWhoamiStatusJSON(t *testing.T) {
+	expires := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	status := whoamiStatus{
+		Authenticated:    true,
+		Subject:          "user_123",
+		Email:            "dev@example.com",
+		Scopes:           []string{"openid", "email"},
+		ExpiresAt:        &expires,
+		ExpiresInSeconds: 600,
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	var decoded map[string]any
+	json.Unmarshal(data, &decoded)
+	for _, key := range []string{"authenticated", "subject", "email", "scopes", "expires_at", "expires_in_seconds"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("whoami JSON missing %q: %s", key, data)
+		}
+	}
+}
@@ -3,7 +3,10 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/bold-minds/trek-go"
@@ -40,35 +43,51 @@ func runInspect(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Warning: Could not create client, using empty session list")
 		decision := trek.Decide(time.Now(), "cli", ctx, nil)
-		printDecision(decision)
-		return nil
+		return printDecision(decision)
 	}
 
-	resp, err := client.GetActiveSessions(cmd.Context(), "cli", "")
+	reqCtx, cancel := withTimeout(cmd.Context())
+	defer cancel()
+
+	resp, err := client.GetActiveSessions(reqCtx, "cli", "")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: Could not fetch sessions: %v\n", err)
 		decision := trek.Decide(time.Now(), "cli", ctx, nil)
-		printDecision(decision)
-		return nil
+		return printDecision(decision)
 	}
 
 	decision := trek.Decide(time.Now(), "cli", ctx, resp.Sessions)
-	printDecision(decision)
+	return printDecision(decision)
+}
 
-	return nil
+type decisionView struct {
+	Matched        bool              `json:"matched" yaml:"matched"`
+	SessionID      string            `json:"session_id" yaml:"session_id"`
+	EffectiveLevel string            `json:"effective_level" yaml:"effective_level"`
+	ReasonCode     string            `json:"reason_code" yaml:"reason_code"`
+	Labels         map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
 }
 
-func printDecision(d trek.Decision) {
-	fmt.Printf("Decision:\n")
-	fmt.Printf("  Matched:         %v\n", d.Matched)
-	fmt.Printf("  Session ID:      %s\n", d.SessionID)
-	fmt.Printf("  Effective Level: %s\n", d.EffectiveLevel)
-	fmt.Printf("  Reason Code:     %s\n", d.ReasonCode)
+func printDecision(d trek.Decision) error {
+	view := decisionView{
+		Matched:        d.Matched,
+		SessionID:      d.SessionID,
+		EffectiveLevel: string(d.EffectiveLevel),
+		ReasonCode:     string(d.ReasonCode),
+		Labels:         d.Labels,
+	}
+	return printItem(view, func(w io.Writer, d decisionView) {
+		fmt.Fprintf(w, "Decision:\n")
+		fmt.Fprintf(w, "  Matched:         %v\n", d.Matched)
+		fmt.Fprintf(w, "  Session ID:      %s\n", d.SessionID)
+		fmt.Fprintf(w, "  Effective Level: %s\n", d.EffectiveLevel)
+		fmt.Fprintf(w, "  Reason Code:     %s\n", d.ReasonCode)
 
-	if len(d.Labels) > 0 {
-		fmt.Printf("  Labels:\n")
-		for k, v := range d.Labels {
-			fmt.Printf("    %s: %s\n", k, v)
+		if len(d.Labels) > 0 {
+			fmt.Fprintf(w, "  Labels:\n")
+			for _, k := range slices.Sorted(maps.Keys(d.Labels)) {
+				fmt.Fprintf(w, "    %s: %s\n", k, d.Labels[k])
+			}
 		}
-	}
+	})
 }
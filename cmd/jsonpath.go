@@ -0,0 +1,554 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// jsonPathTemplate is a kubectl-style JSONPath template: literal text with
+// {expressions} evaluated against the JSON form of the output. Supported
+// expressions are fields (.a, ['a']), indexes ([0], [-1]), slices ([1:3]),
+// wildcards ([*], .*), filters ([?(@.status=="active")]), string literals
+// ({"\n"}) and {range path}...{end}, which renders its body once per match
+// with paths relative to that match; {@} is the match itself.
+type jsonPathTemplate struct {
+	parts []jsonPathPart
+}
+
+type jsonPathPart struct {
+	literal string
+	path    []jsonPathStep // nil for literal text
+	expr    string
+
+	// isRange marks {range path}; body is the template up to its {end}.
+	isRange bool
+	body    []jsonPathPart
+}
+
+type jsonPathStepKind int
+
+const (
+	stepField jsonPathStepKind = iota
+	stepIndex
+	stepSlice
+	stepWildcard
+	stepFilter
+)
+
+type jsonPathStep struct {
+	kind       jsonPathStepKind
+	name       string
+	index      int
+	start, end *int
+	filter     *jsonPathFilter
+}
+
+// jsonPathFilter is [?(@.path op value)], or [?(@.path)] for existence.
+type jsonPathFilter struct {
+	path  []jsonPathStep
+	op    string
+	value any
+}
+
+func parseJSONPathTemplate(text string) (*jsonPathTemplate, error) {
+	template := text
+	// stack holds the open {range} parts; the first entry collects the
+	// top level.
+	stack := []*jsonPathPart{{}}
+	add := func(part jsonPathPart) {
+		top := stack[len(stack)-1]
+		top.body = append(top.body, part)
+	}
+
+	for len(text) > 0 {
+		open := strings.IndexByte(text, '{')
+		if open < 0 {
+			add(jsonPathPart{literal: text})
+			break
+		}
+		if open > 0 {
+			add(jsonPathPart{literal: text[:open]})
+		}
+		end := matchingBrace(text, open)
+		if end < 0 {
+			return nil, fmt.Errorf("invalid jsonpath %q: unclosed {", text)
+		}
+		expr := strings.TrimSpace(text[open+1 : end])
+		text = text[end+1:]
+
+		switch {
+		case strings.HasPrefix(expr, `"`):
+			s, err := strconv.Unquote(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid jsonpath string %s: %w", expr, err)
+			}
+			add(jsonPathPart{literal: s})
+		case expr == "end":
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("invalid jsonpath %q: {end} without {range}", template)
+			}
+			done := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			add(*done)
+		case strings.HasPrefix(expr, "range "):
+			path, err := parseJSONPath(strings.TrimSpace(strings.TrimPrefix(expr, "range ")))
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, &jsonPathPart{path: path, expr: expr, isRange: true})
+		default:
+			path, err := parseJSONPath(expr)
+			if err != nil {
+				return nil, err
+			}
+			add(jsonPathPart{path: path, expr: expr})
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("invalid jsonpath %q: {%s} has no {end}", template, stack[len(stack)-1].expr)
+	}
+	return &jsonPathTemplate{parts: stack[0].body}, nil
+}
+
+// matchingBrace returns the index of the } closing the { at open, skipping
+// quoted strings.
+func matchingBrace(s string, open int) int {
+	var quote byte
+	for i := open + 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+func parseJSONPath(expr string) ([]jsonPathStep, error) {
+	// $ is the document and @ the current value; both are where paths start.
+	p := expr
+	if strings.HasPrefix(p, "$") || strings.HasPrefix(p, "@") {
+		p = p[1:]
+	}
+	if p == "" {
+		return []jsonPathStep{}, nil
+	}
+
+	var steps []jsonPathStep
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			if strings.HasPrefix(p, ".") {
+				return nil, fmt.Errorf("invalid jsonpath %q: recursive descent (..) is not supported", expr)
+			}
+			if strings.HasPrefix(p, "*") {
+				steps = append(steps, jsonPathStep{kind: stepWildcard})
+				p = p[1:]
+				continue
+			}
+			n := 0
+			for n < len(p) && p[n] != '.' && p[n] != '[' {
+				n++
+			}
+			if n == 0 {
+				if len(p) == 0 && len(steps) == 0 {
+					// "{.}" is the whole document.
+					return []jsonPathStep{}, nil
+				}
+				return nil, fmt.Errorf("invalid jsonpath %q: empty field name", expr)
+			}
+			steps = append(steps, jsonPathStep{kind: stepField, name: p[:n]})
+			p = p[n:]
+		case '[':
+			end := matchingBracket(p)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid jsonpath %q: unclosed [", expr)
+			}
+			step, err := parseJSONPathBracket(strings.TrimSpace(p[1:end]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid jsonpath %q: %w", expr, err)
+			}
+			steps = append(steps, step)
+			p = p[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid jsonpath %q: expected . or [ at %q", expr, p)
+		}
+	}
+	return steps, nil
+}
+
+func matchingBracket(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseJSONPathBracket(inner string) (jsonPathStep, error) {
+	switch {
+	case inner == "*":
+		return jsonPathStep{kind: stepWildcard}, nil
+	case strings.HasPrefix(inner, "'") || strings.HasPrefix(inner, `"`):
+		name, err := unquoteJSONPath(inner)
+		if err != nil {
+			return jsonPathStep{}, err
+		}
+		return jsonPathStep{kind: stepField, name: name}, nil
+	case strings.HasPrefix(inner, "?(") && strings.HasSuffix(inner, ")"):
+		filter, err := parseJSONPathFilter(strings.TrimSpace(inner[2 : len(inner)-1]))
+		if err != nil {
+			return jsonPathStep{}, err
+		}
+		return jsonPathStep{kind: stepFilter, filter: filter}, nil
+	case strings.Contains(inner, ":"):
+		lo, hi, _ := strings.Cut(inner, ":")
+		step := jsonPathStep{kind: stepSlice}
+		for _, b := range []struct {
+			text   string
+			target **int
+		}{{lo, &step.start}, {hi, &step.end}} {
+			if b.text = strings.TrimSpace(b.text); b.text == "" {
+				continue
+			}
+			n, err := strconv.Atoi(b.text)
+			if err != nil {
+				return jsonPathStep{}, fmt.Errorf("invalid slice bound %q", b.text)
+			}
+			*b.target = &n
+		}
+		return step, nil
+	default:
+		n, err := strconv.Atoi(inner)
+		if err != nil {
+			return jsonPathStep{}, fmt.Errorf("invalid index %q", inner)
+		}
+		return jsonPathStep{kind: stepIndex, index: n}, nil
+	}
+}
+
+func unquoteJSONPath(s string) (string, error) {
+	if strings.HasPrefix(s, "'") {
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return s[1 : len(s)-1], nil
+	}
+	return strconv.Unquote(s)
+}
+
+var jsonPathOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func parseJSONPathFilter(expr string) (*jsonPathFilter, error) {
+	filter := &jsonPathFilter{}
+	left := expr
+	if i, op := findFilterOp(expr); i >= 0 {
+		left, filter.op = strings.TrimSpace(expr[:i]), op
+		value, err := parseJSONPathLiteral(strings.TrimSpace(expr[i+len(op):]))
+		if err != nil {
+			return nil, err
+		}
+		filter.value = value
+	}
+
+	if !strings.HasPrefix(left, "@") {
+		return nil, fmt.Errorf("filter %q must start with @", expr)
+	}
+	path, err := parseJSONPath(left[1:])
+	if err != nil {
+		return nil, err
+	}
+	filter.path = path
+	return filter, nil
+}
+
+// findFilterOp returns the position and operator of the first comparison in
+// a filter, skipping quoted strings, or -1 if there is none.
+func findFilterOp(expr string) (int, string) {
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		default:
+			for _, op := range jsonPathOps {
+				if strings.HasPrefix(expr[i:], op) {
+					return i, op
+				}
+			}
+		}
+	}
+	return -1, ""
+}
+
+func parseJSONPathLiteral(s string) (any, error) {
+	switch {
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`):
+		return unquoteJSONPath(s)
+	case s == "true" || s == "false":
+		return s == "true", nil
+	case s == "null":
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter value %q", s)
+	}
+	return n, nil
+}
+
+// execute renders the template against v, which is converted to its JSON
+// form first so paths use the same field names as -o json.
+func (t *jsonPathTemplate) execute(v any) (string, error) {
+	doc, err := toJSONValue(v)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err := renderJSONPath(&out, t.parts, doc); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func renderJSONPath(out *strings.Builder, parts []jsonPathPart, doc any) error {
+	for _, part := range parts {
+		switch {
+		case part.isRange:
+			for _, item := range evalJSONPath(part.path, []any{doc}) {
+				if err := renderJSONPath(out, part.body, item); err != nil {
+					return err
+				}
+			}
+		case part.path == nil:
+			out.WriteString(part.literal)
+		default:
+			results := evalJSONPath(part.path, []any{doc})
+			if len(results) == 0 && !jsonPathIsMulti(part.path) {
+				return fmt.Errorf("jsonpath {%s}: not found", part.expr)
+			}
+			for i, r := range results {
+				if i > 0 {
+					out.WriteByte(' ')
+				}
+				out.WriteString(formatJSONPathValue(r))
+			}
+		}
+	}
+	return nil
+}
+
+func toJSONValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode output: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode output: %w", err)
+	}
+	return doc, nil
+}
+
+// jsonPathIsMulti reports whether a path can legitimately match nothing.
+func jsonPathIsMulti(path []jsonPathStep) bool {
+	for _, s := range path {
+		if s.kind == stepWildcard || s.kind == stepSlice || s.kind == stepFilter {
+			return true
+		}
+	}
+	return false
+}
+
+func evalJSONPath(path []jsonPathStep, values []any) []any {
+	for _, step := range path {
+		var next []any
+		for _, v := range values {
+			next = append(next, applyJSONPathStep(step, v)...)
+		}
+		values = next
+	}
+	return values
+}
+
+func applyJSONPathStep(step jsonPathStep, v any) []any {
+	switch step.kind {
+	case stepField:
+		if m, ok := v.(map[string]any); ok {
+			if child, ok := m[step.name]; ok {
+				return []any{child}
+			}
+		}
+	case stepIndex:
+		if a, ok := v.([]any); ok {
+			i := step.index
+			if i < 0 {
+				i += len(a)
+			}
+			if i >= 0 && i < len(a) {
+				return []any{a[i]}
+			}
+		}
+	case stepSlice:
+		if a, ok := v.([]any); ok {
+			lo, hi := 0, len(a)
+			if step.start != nil {
+				lo = clampIndex(*step.start, len(a))
+			}
+			if step.end != nil {
+				hi = clampIndex(*step.end, len(a))
+			}
+			if lo < hi {
+				return a[lo:hi]
+			}
+		}
+	case stepWildcard:
+		switch c := v.(type) {
+		case []any:
+			return c
+		case map[string]any:
+			keys := slices.Sorted(maps.Keys(c))
+			out := make([]any, len(keys))
+			for i, k := range keys {
+				out[i] = c[k]
+			}
+			return out
+		}
+	case stepFilter:
+		a, ok := v.([]any)
+		if !ok {
+			return nil
+		}
+		var out []any
+		for _, item := range a {
+			if step.filter.matches(item) {
+				out = append(out, item)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	return max(0, min(i, n))
+}
+
+func (f *jsonPathFilter) matches(item any) bool {
+	results := evalJSONPath(f.path, []any{item})
+	if len(results) == 0 {
+		return false
+	}
+	if f.op == "" {
+		return true
+	}
+	got := results[0]
+
+	if n, ok := got.(json.Number); ok {
+		want, ok := f.value.(float64)
+		if !ok {
+			return f.op == "!="
+		}
+		g, _ := n.Float64()
+		switch f.op {
+		case "==":
+			return g == want
+		case "!=":
+			return g != want
+		case "<":
+			return g < want
+		case ">":
+			return g > want
+		case "<=":
+			return g <= want
+		case ">=":
+			return g >= want
+		}
+	}
+
+	if s, ok := got.(string); ok {
+		if want, ok := f.value.(string); ok {
+			switch f.op {
+			case "==":
+				return s == want
+			case "!=":
+				return s != want
+			case "<":
+				return s < want
+			case ">":
+				return s > want
+			case "<=":
+				return s <= want
+			case ">=":
+				return s >= want
+			}
+		}
+	}
+
+	switch f.op {
+	case "==":
+		return got == f.value
+	case "!=":
+		return got != f.value
+	}
+	return false
+}
+
+// formatJSONPathValue prints strings and numbers bare, and objects and
+// arrays as compact JSON.
+func formatJSONPathValue(v any) string {
+	switch c := v.(type) {
+	case string:
+		return c
+	case json.Number:
+		return c.String()
+	case nil:
+		return ""
+	case bool:
+		return strconv.FormatBool(c)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
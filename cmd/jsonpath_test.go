@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestJSONPathTemplate(t *testing.T) {
+	doc := map[string]any{
+		"items": []map[string]any{
+			{"id": "sess_1", "status": "active", "level": "debug", "caps": map[string]any{"max": 10}},
+			{"id": "sess_2", "status": "expired", "level": "trace", "caps": map[string]any{"max": 50}},
+			{"id": "sess_3", "status": "active", "level": "trace", "caps": map[string]any{"max": 5}},
+		},
+		"name": "sessions",
+	}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{.name}", "sessions"},
+		{"{$.name}", "sessions"},
+		{"{.items[*].id}", "sess_1 sess_2 sess_3"},
+		{"{.items[0].id}", "sess_1"},
+		{"{.items[-1].id}", "sess_3"},
+		{"{.items[1:].id}", "sess_2 sess_3"},
+		{"{.items[:1].id}", "sess_1"},
+		{"{.items[0]['status']}", "active"},
+		{`{.items[?(@.status=="active")].id}`, "sess_1 sess_3"},
+		{`{.items[?(@.caps.max>=10)].id}`, "sess_1 sess_2"},
+		{`{.items[?(@.level!='trace')].id}`, "sess_1"},
+		{"{.items[0].caps}", `{"max":10}`},
+		{`{.items[*].caps.max}`, "10 50 5"},
+		{`id={.items[0].id}{"\n"}`, "id=sess_1\n"},
+		{`{.items[?(@.status=="gone")].id}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := parseJSONPathTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			got, err := tmpl.execute(doc)
+			if err != nil {
+				t.Fatalf("execute error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathFilters(t *testing.T) {
+	doc := map[string]any{
+		"items": []map[string]any{
+			{"id": "a", "status": "a==b", "count": 3, "live": true, "labels": map[string]any{"team": "pay"}},
+			{"id": "b", "status": "x<y", "count": 7, "live": false, "owner": nil},
+			{"id": "c", "status": "it's", "count": 10, "live": true},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"operator inside double quotes", `{.items[?(@.status!="a==b")].id}`, "b c"},
+		{"operator inside single quotes", `{.items[?(@.status=='x<y')].id}`, "b"},
+		{"quote inside quotes", `{.items[?(@.status=="it's")].id}`, "c"},
+		{"less than", `{.items[?(@.count<7)].id}`, "a"},
+		{"greater than", `{.items[?(@.count>3)].id}`, "b c"},
+		{"less or equal", `{.items[?(@.count<=7)].id}`, "a b"},
+		{"spaces around operator", `{.items[?(@.count == 10)].id}`, "c"},
+		{"bool", `{.items[?(@.live==true)].id}`, "a c"},
+		{"null", `{.items[?(@.owner==null)].id}`, "b"},
+		{"exists", `{.items[?(@.labels)].id}`, "a"},
+		{"nested field", `{.items[?(@.labels.team=="pay")].id}`, "a"},
+		{"bracket field", `{.items[?(@['status']=="x<y")].id}`, "b"},
+		{"number against string", `{.items[?(@.count!="3")].id}`, "a b c"},
+		{"string against number", `{.items[?(@.status==3)].id}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseJSONPathTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			got, err := tmpl.execute(doc)
+			if err != nil {
+				t.Fatalf("execute error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathSlicesAndWildcards(t *testing.T) {
+	doc := map[string]any{
+		"n":      []int{0, 1, 2, 3, 4},
+		"labels": map[string]any{"team": "pay", "env": "dev", "app": "checkout"},
+		"empty":  []int{},
+	}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{.n[1:3]}", "1 2"},
+		{"{.n[-2:]}", "3 4"},
+		{"{.n[:-3]}", "0 1"},
+		{"{.n[3:1]}", ""},
+		{"{.n[2:100]}", "2 3 4"},
+		{"{.n[-100:1]}", "0"},
+		{"{.n[:]}", "0 1 2 3 4"},
+		{"{.labels.*}", "checkout dev pay"},
+		{"{.labels[*]}", "checkout dev pay"},
+		{"{.empty[*]}", ""},
+		{"{.name[*]}", ""},
+		{"{.}", `{"empty":[],"labels":{"app":"checkout","env":"dev","team":"pay"},"n":[0,1,2,3,4]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := parseJSONPathTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			got, err := tmpl.execute(doc)
+			if err != nil {
+				t.Fatalf("execute error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	tmpl, _ := parseJSONPathTemplate("{.n[5]}")
+	if _, err := tmpl.execute(doc); err == nil || !contains(err.Error(), "not found") {
+		t.Errorf("out-of-range index error = %v, want not found", err)
+	}
+}
+
+func TestJSONPathRange(t *testing.T) {
+	doc := map[string]any{
+		"items": []map[string]any{
+			{"id": "sess_1", "status": "active", "labels": map[string]any{"team": "pay", "env": "dev"}},
+			{"id": "sess_2", "status": "expired", "labels": map[string]any{"team": "ops"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"lines", `{range .items[*]}{.id}{"\t"}{.status}{"\n"}{end}`, "sess_1\tactive\nsess_2\texpired\n"},
+		{"literal text", `{range .items[*]}[{.id}] {end}`, "[sess_1] [sess_2] "},
+		{"filtered", `{range .items[?(@.status=="active")]}{.id}{end}`, "sess_1"},
+		{"nested", `{range .items[*]}{.id}:{range .labels.*}<{@}>{end};{end}`, "sess_1:<dev><pay>;sess_2:<ops>;"},
+		{"text around", `ids: {range .items[*]}{.id} {end}done`, "ids: sess_1 sess_2 done"},
+		{"no matches", `{range .none[*]}{.id}{end}empty`, "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseJSONPathTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			got, err := tmpl.execute(doc)
+			if err != nil {
+				t.Fatalf("execute error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	tmpl, _ := parseJSONPathTemplate(`{range .items[*]}{.labels.env}{end}`)
+	if _, err := tmpl.execute(doc); err == nil || !contains(err.Error(), "not found") {
+		t.Errorf("missing field inside range error = %v, want not found", err)
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  string
+	}{
+		{"{.items[0", "unclosed {"},
+		{"{.items", "unclosed {"},
+		{"{..id}", "recursive descent"},
+		{"{items}", "expected . or ["},
+		{"{.a.}", "empty field name"},
+		{"{.items[0}]", "unclosed ["},
+		{`{.items[?(status=="x")]}`, "must start with @"},
+		{`{.items[?(@.status==active)]}`, `invalid filter value "active"`},
+		{"{.items[a:2]}", `invalid slice bound "a"`},
+		{"{.items[one]}", `invalid index "one"`},
+		{`{"\q"}`, "invalid jsonpath string"},
+		{"{range .items[*]}{.id}", "has no {end}"},
+		{"{.id}{end}", "{end} without {range}"},
+		{"{range .items[*]}{range .x[*]}{end}", "has no {end}"},
+		{"{range items}{end}", "expected . or ["},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			_, err := parseJSONPathTemplate(tt.template)
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseJSONPathTemplate(%q) error = %v, want %q", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+
+	tmpl, _ := parseJSONPathTemplate("{.missing}")
+	if _, err := tmpl.execute(map[string]any{}); err == nil || !contains(err.Error(), "not found") {
+		t.Errorf("execute() of missing field error = %v, want not found", err)
+	}
+}
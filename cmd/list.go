@@ -3,6 +3,9 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"maps"
+	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,17 +16,41 @@ import (
 var (
 	statusFilter string
 	watchMode    bool
+	listColumns  []string
+	listSortBy   string
+
+	listLabelSelector string
+	listUser          string
+	listTenant        string
+	listRoute         string
+	listLevel         string
+	listMine          bool
 )
 
 var sessionListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List debug sessions",
-	Long: `List debug sessions with optional status filter.
+	Long: `List debug sessions, optionally filtered.
+
+-l takes a comma-separated label selector: key=value, key!=value, key (has
+the label) and !key (lacks it). Filters combine; a session must match all.
+--status is sent to the API; the other filters are applied locally.
 
 Examples:
   trek session list
   trek session list --status active
-  trek session list --watch`,
+  trek session list -l team=payments,env!=dev
+  trek session list --user u123 --level trace
+  trek session list --mine --status active
+  trek session list --watch
+  trek session list -o wide
+  trek session list -o ndjson
+  trek session list --columns id,status,expires,selector,reason --sort-by expires
+
+Columns: id, status, level, expires, selector, labels, reason, created-by,
+created, max-request, max-session. Without --columns, -o wide adds the
+columns after selector and never truncates. Tables fit the terminal width
+(or COLUMNS), truncating IDs and selectors only when they don't fit.`,
 	RunE: runList,
 }
 
@@ -32,53 +59,123 @@ func init() {
 
 	sessionListCmd.Flags().StringVar(&statusFilter, "status", "", "Filter by status (active, revoked, expired)")
 	sessionListCmd.Flags().BoolVar(&watchMode, "watch", false, "Watch for changes (refresh every 2s)")
+	sessionListCmd.Flags().StringSliceVar(&listColumns, "columns", nil, "Comma-separated columns to show, in order")
+	sessionListCmd.Flags().StringVar(&listSortBy, "sort-by", "", "Sort by expires, created or level")
+	sessionListCmd.Flags().StringVarP(&listLabelSelector, "label", "l", "", "Label selector, e.g. team=payments,env!=dev")
+	sessionListCmd.Flags().StringVar(&listUser, "user", "", "Only sessions targeting this user ID")
+	sessionListCmd.Flags().StringVar(&listTenant, "tenant", "", "Only sessions targeting this tenant ID")
+	sessionListCmd.Flags().StringVar(&listRoute, "route", "", "Only sessions targeting this route")
+	sessionListCmd.Flags().StringVar(&listLevel, "level", "", "Only sessions at this level (debug or trace)")
+	sessionListCmd.Flags().BoolVar(&listMine, "mine", false, "Only sessions you created")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
+	if _, err := selectColumns(sessionColumns, listColumns); err != nil {
+		return &cliError{Code: codeUsage, ExitCode: exitUsage, Err: err}
+	}
+	if _, ok := sessionSortKeys[listSortBy]; listSortBy != "" && !ok {
+		return usageError("invalid --sort-by %q: expected expires, created or level", listSortBy)
+	}
+	filter, err := newListFilter(loginIdentities)
+	if err != nil {
+		return err
+	}
+
 	client, err := getClient()
 	if err != nil {
 		return err
 	}
 
 	if watchMode {
-		return runListWatch(cmd.Context(), client)
+		return runListWatch(cmd.Context(), client, filter)
 	}
 
-	return listSessionsOnce(cmd.Context(), client)
+	return listSessionsOnce(cmd.Context(), client, filter)
 }
 
-func listSessionsOnce(ctx context.Context, client *trek.Client) error {
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+// newListFilter builds the filter from the list flags. identities resolves
+// --mine.
+func newListFilter(identities func() ([]string, error)) (sessionFilter, error) {
+	var f sessionFilter
+	switch statusFilter {
+	case "", "active", "revoked", "expired":
+	default:
+		return f, usageError("invalid --status %q: expected active, revoked or expired", statusFilter)
+	}
+	f.status = statusFilter
+
+	labels, err := parseLabelSelector(listLabelSelector)
+	if err != nil {
+		return f, err
+	}
+	f.labels = labels
+
+	if err := validateLevel(listLevel); err != nil {
+		return f, err
+	}
+	f.level = listLevel
+	f.selector = trek.Selector{UserID: listUser, TenantID: listTenant, Route: listRoute}
+
+	if listMine {
+		if f.createdBy, err = identities(); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// fetchSessions lists sessions, passing the status to the API, and applies
+// the rest of filter locally. Sessions the server returns without a status
+// take the one it filtered on; otherwise the status is checked again in case
+// the server ignores it.
+func fetchSessions(ctx context.Context, client *trek.Client, filter sessionFilter) ([]trek.Session, error) {
+	ctx, cancel := withTimeout(ctx)
 	defer cancel()
 
-	sessions, err := client.ListSessions(ctx, statusFilter)
+	sessions, err := client.ListSessions(ctx, filter.status)
 	if err != nil {
-		return fmt.Errorf("failed to list sessions: %w", err)
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for i := range sessions {
+		if sessions[i].Status == "" {
+			sessions[i].Status = filter.status
+		}
 	}
+	return filter.filter(sessions), nil
+}
 
-	printSessionTable(sessions)
-	return nil
+func listSessionsOnce(ctx context.Context, client *trek.Client, filter sessionFilter) error {
+	sessions, err := fetchSessions(ctx, client, filter)
+	if err != nil {
+		return err
+	}
+
+	return printSessions(sessions)
 }
 
-func runListWatch(ctx context.Context, client *trek.Client) error {
+func runListWatch(ctx context.Context, client *trek.Client, filter sessionFilter) error {
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
-	fmt.Println("Watching sessions (Ctrl+C to stop)...")
-	fmt.Println()
+	// Machine-readable formats stream one snapshot per poll instead of
+	// redrawing the screen.
+	human := humanOutput()
+	if human {
+		fmt.Println("Watching sessions (Ctrl+C to stop)...")
+		fmt.Println()
+	}
 
 	for {
-		fmt.Print("\033[H\033[2J")
-		fmt.Printf("Sessions (updated %s)\n\n", time.Now().Format("15:04:05"))
-
-		listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-		sessions, err := client.ListSessions(listCtx, statusFilter)
-		cancel()
+		if human {
+			fmt.Print("\033[H\033[2J")
+			fmt.Printf("Sessions (updated %s)\n\n", time.Now().Format("15:04:05"))
+		}
 
+		sessions, err := fetchSessions(ctx, client, filter)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-		} else {
-			printSessionTable(sessions)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else if err := printSessions(sessions); err != nil {
+			return err
 		}
 
 		select {
@@ -89,30 +186,20 @@ func runListWatch(ctx context.Context, client *trek.Client) error {
 	}
 }
 
-func printSessionTable(sessions []trek.Session) {
-	if len(sessions) == 0 {
-		fmt.Println("No sessions found")
-		return
+func printSessions(sessions []trek.Session) error {
+	views := newSessionViews(sessions)
+	if listSortBy != "" {
+		sortSessions(views, listSortBy)
 	}
 
-	fmt.Printf("%-28s %-8s %-8s %-25s %s\n", "ID", "STATUS", "LEVEL", "EXPIRES", "SELECTOR")
-	fmt.Println("--------------------------------------------------------------------------------------------")
-
-	for _, s := range sessions {
-		status := "active"
-		if time.Now().After(s.ExpiresAt) {
-			status = "expired"
+	columns := sessionColumns
+	if len(listColumns) > 0 {
+		var err error
+		if columns, err = selectColumns(sessionColumns, listColumns); err != nil {
+			return err
 		}
-
-		selectorStr := formatSelector(s.Selector)
-		fmt.Printf("%-28s %-8s %-8s %-25s %s\n",
-			truncate(s.ID, 28),
-			status,
-			s.Level,
-			s.ExpiresAt.Format("2006-01-02 15:04:05"),
-			truncate(selectorStr, 30),
-		)
 	}
+	return printList(views, columns, "No sessions found")
 }
 
 func formatSelector(s trek.Selector) string {
@@ -129,10 +216,8 @@ func formatSelector(s trek.Selector) string {
 	if s.Route != "" {
 		parts = append(parts, "route:"+s.Route)
 	}
-	if len(s.Custom) > 0 {
-		for k, v := range s.Custom {
-			parts = append(parts, k+":"+v)
-		}
+	for _, k := range slices.Sorted(maps.Keys(s.Custom)) {
+		parts = append(parts, k+":"+s.Custom[k])
 	}
 	if len(parts) == 0 {
 		return "(empty)"
@@ -140,9 +225,12 @@ func formatSelector(s trek.Selector) string {
 	return strings.Join(parts, ", ")
 }
 
+// truncate shortens s to max characters, counted in runes like the table
+// widths, so multibyte text is never cut mid-character.
 func truncate(s string, max int) string {
-	if len(s) <= max {
+	runes := []rune(s)
+	if len(runes) <= max {
 		return s
 	}
-	return s[:max-3] + "..."
+	return string(runes[:max-3]) + "..."
 }
@@ -1,7 +1,12 @@
 package cmd
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bold-minds/trek-go"
 )
@@ -168,6 +173,18 @@ func TestTruncate(t *testing.T) {
 			max:   4,
 			want:  "h...",
 		},
+		{
+			name:  "multibyte fits",
+			input: "équipe",
+			max:   6,
+			want:  "équipe",
+		},
+		{
+			name:  "multibyte truncated",
+			input: "région=亚太地区",
+			max:   10,
+			want:  "région=...",
+		},
 	}
 
 	for _, tt := range tests {
@@ -190,3 +207,341 @@ func TestStatusFilterValues(t *testing.T) {
 		})
 	}
 }
+
+func TestSelectColumns(t *testing.T) {
+	columns, err := selectColumns(sessionColumns, []string{"ID", "reason", "selector"})
+	if err != nil {
+		t.Fatalf("selectColumns() error: %v", err)
+	}
+	var headers []string
+	for _, c := range columns {
+		headers = append(headers, c.Header)
+		if c.Wide {
+			t.Errorf("selected column %s still wide-only", c.Name)
+		}
+	}
+	if strings.Join(headers, ",") != "ID,REASON,SELECTOR" {
+		t.Errorf("headers = %v, want ID,REASON,SELECTOR", headers)
+	}
+
+	if _, err := selectColumns(sessionColumns, []string{"owner"}); err == nil || !contains(err.Error(), "created-by") {
+		t.Errorf("selectColumns(owner) error = %v, want list of columns", err)
+	}
+}
+
+func TestSortSessions(t *testing.T) {
+	now := time.Now()
+	views := []sessionView{
+		{ID: "c", Level: "trace", ExpiresAt: now.Add(time.Hour), CreatedAt: now.Add(-time.Minute)},
+		{ID: "a", Level: "debug", ExpiresAt: now.Add(3 * time.Hour), CreatedAt: now.Add(-time.Hour)},
+		{ID: "b", Level: "trace", ExpiresAt: now.Add(2 * time.Hour), CreatedAt: now.Add(-2 * time.Hour)},
+	}
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"expires", "c,b,a"},
+		{"created", "b,a,c"},
+		{"level", "a,b,c"},
+	}
+	for _, tt := range tests {
+		sortSessions(views, tt.key)
+		var ids []string
+		for _, v := range views {
+			ids = append(ids, v.ID)
+		}
+		if strings.Join(ids, ",") != tt.want {
+			t.Errorf("sort by %s = %v, want %s", tt.key, ids, tt.want)
+		}
+	}
+}
+
+func TestRunListValidatesColumnsAndSort(t *testing.T) {
+	defer func() { listColumns, listSortBy = nil, "" }()
+
+	listColumns = []string{"nope"}
+	if err := runList(sessionListCmd, nil); err == nil || !contains(err.Error(), "unknown column") {
+		t.Errorf("runList() error = %v, want unknown column", err)
+	}
+
+	listColumns, listSortBy = nil, "name"
+	if err := runList(sessionListCmd, nil); err == nil || !contains(err.Error(), "--sort-by") {
+		t.Errorf("runList() error = %v, want invalid --sort-by", err)
+	}
+}
+
+func TestSessionTableFitsTerminal(t *testing.T) {
+	longRoute := "/api/v2/organizations/{org}/projects/{project}/deployments/*"
+	sessions := []trek.Session{{
+		ID:        "sess_0123456789abcdefghijklmnopqrstuvwxyz",
+		Level:     trek.LevelDebug,
+		ExpiresAt: time.Now().Add(time.Hour),
+		Selector:  trek.Selector{Route: longRoute},
+		Reason:    "investigating slow deployments",
+	}}
+
+	tests := []struct {
+		name        string
+		format      string
+		columns     string
+		wantFull    bool
+		maxLineSize int
+	}{
+		{name: "no terminal truncates", format: outputTable, wantFull: false},
+		{name: "wide terminal shows everything", format: outputTable, columns: "200", wantFull: true},
+		{name: "narrow terminal truncates", format: outputTable, columns: "100", wantFull: false, maxLineSize: 100},
+		{name: "wide output never truncates", format: outputWide, columns: "80", wantFull: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("COLUMNS", tt.columns)
+			buf := captureOutput(t, tt.format)
+
+			if err := printSessions(sessions); err != nil {
+				t.Fatalf("printSessions() error: %v", err)
+			}
+			out := buf.String()
+			if full := strings.Contains(out, longRoute) && strings.Contains(out, sessions[0].ID); full != tt.wantFull {
+				t.Errorf("full selector and ID shown = %v, want %v:\n%s", full, tt.wantFull, out)
+			}
+			if tt.maxLineSize > 0 {
+				for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+					if len(line) > tt.maxLineSize {
+						t.Errorf("line is %d wide, want at most %d:\n%s", len(line), tt.maxLineSize, line)
+					}
+				}
+			}
+		})
+	}
+}
+
+// setListFilters sets the list filter flags for one test.
+func setListFilters(t *testing.T, status, labelSelector, user, lvl string, mine bool) {
+	t.Helper()
+	oldStatus, oldLabels, oldUser, oldTenant, oldRoute, oldLevel, oldMine := statusFilter, listLabelSelector, listUser, listTenant, listRoute, listLevel, listMine
+	t.Cleanup(func() {
+		statusFilter, listLabelSelector, listUser, listTenant, listRoute, listLevel, listMine = oldStatus, oldLabels, oldUser, oldTenant, oldRoute, oldLevel, oldMine
+	})
+	statusFilter, listLabelSelector, listUser, listTenant, listRoute, listLevel, listMine = status, labelSelector, user, "", "", lvl, mine
+}
+
+func TestParseLabelSelector(t *testing.T) {
+	labels := map[string]string{"team": "payments", "env": "prod"}
+
+	tests := []struct {
+		selector string
+		want     bool
+		wantErr  bool
+	}{
+		{selector: "", want: true},
+		{selector: "team=payments", want: true},
+		{selector: "team==payments", want: true},
+		{selector: "team=search", want: false},
+		{selector: "team=payments,env!=dev", want: true},
+		{selector: "team=payments, env!=prod", want: false},
+		{selector: "owner!=alice", want: true},
+		{selector: "env", want: true},
+		{selector: "owner", want: false},
+		{selector: "!owner", want: true},
+		{selector: "!env", want: false},
+		{selector: "=payments", wantErr: true},
+		{selector: "!", wantErr: true},
+		{selector: "team!=a=b", wantErr: false, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			reqs, err := parseLabelSelector(tt.selector)
+			if tt.wantErr {
+				if got := classifyError(err); err == nil || got.ExitCode != exitUsage {
+					t.Fatalf("parseLabelSelector(%q) error = %v, want a usage error", tt.selector, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLabelSelector(%q) error = %v", tt.selector, err)
+			}
+			got := sessionFilter{labels: reqs}.matches(trek.Session{Labels: labels, ExpiresAt: time.Now().Add(time.Hour)})
+			if got != tt.want {
+				t.Errorf("selector %q matches = %v, want %v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionStatusPrefersAPIStatus(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name    string
+		session trek.Session
+		want    string
+	}{
+		{"revoked before expiry", trek.Session{Status: "revoked", ExpiresAt: future}, "revoked"},
+		{"no status, not expired", trek.Session{ExpiresAt: future}, "active"},
+		{"no status, expired", trek.Session{ExpiresAt: time.Now().Add(-time.Minute)}, "expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionStatus(tt.session); got != tt.want {
+				t.Errorf("sessionStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewListFilterErrors(t *testing.T) {
+	noLogin := func() ([]string, error) { return nil, authError("not logged in") }
+
+	tests := []struct {
+		name     string
+		status   string
+		labels   string
+		level    string
+		mine     bool
+		wantExit int
+	}{
+		{name: "bad status", status: "gone", wantExit: exitUsage},
+		{name: "bad label selector", labels: "team=a,=b", wantExit: exitUsage},
+		{name: "bad level", level: "info", wantExit: exitUsage},
+		{name: "mine without login", mine: true, wantExit: exitAuth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setListFilters(t, tt.status, tt.labels, "", tt.level, tt.mine)
+			_, err := newListFilter(noLogin)
+			if got := classifyError(err); err == nil || got.ExitCode != tt.wantExit {
+				t.Errorf("newListFilter() error = %v, want exit code %d", err, tt.wantExit)
+			}
+		})
+	}
+}
+
+func TestRunListFilters(t *testing.T) {
+	startDevServer(t, trek.Policy{})
+	buf := captureOutput(t, outputJSON)
+	client, err := getClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, req := range []trek.CreateSessionRequest{
+		{Selector: trek.Selector{UserID: "u1"}, Level: trek.LevelDebug, TTLSeconds: 600, Labels: map[string]string{"team": "payments", "env": "prod"}},
+		{Selector: trek.Selector{UserID: "u2"}, Level: trek.LevelTrace, TTLSeconds: 600, Labels: map[string]string{"team": "payments", "env": "dev"}},
+		{Selector: trek.Selector{UserID: "u1"}, Level: trek.LevelTrace, TTLSeconds: 600, Labels: map[string]string{"team": "search"}},
+	} {
+		resp, err := client.CreateSession(t.Context(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, resp.ID)
+	}
+	if err := client.RevokeSession(t.Context(), ids[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		status string
+		labels string
+		user   string
+		level  string
+		want   []int
+	}{
+		{name: "all", want: []int{0, 1, 2}},
+		{name: "label selector", labels: "team=payments,env!=dev", want: []int{0}},
+		{name: "user", user: "u1", want: []int{0, 2}},
+		{name: "level", level: "trace", want: []int{1, 2}},
+		{name: "revoked", status: "revoked", want: []int{2}},
+		{name: "active trace", status: "active", level: "trace", want: []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setListFilters(t, tt.status, tt.labels, tt.user, tt.level, false)
+			if err := runList(sessionListCmd, nil); err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			var list listView[sessionView]
+			decodeOutput(t, buf, &list)
+			var got, want []string
+			for _, s := range list.Items {
+				got = append(got, s.ID)
+				if s.ID == ids[2] && s.Status != "revoked" {
+					t.Errorf("revoked session listed as %q", s.Status)
+				}
+			}
+			for _, i := range tt.want {
+				want = append(want, ids[i])
+			}
+			if strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("listed %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRunListRevokedBeforeExpiry(t *testing.T) {
+	srv := startDevServer(t, trek.Policy{})
+	buf := captureOutput(t, outputJSON)
+	client, err := getClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.CreateSession(t.Context(), trek.CreateSessionRequest{Selector: trek.Selector{UserID: "u1"}, Level: trek.LevelDebug, TTLSeconds: 600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Revoked without moving the expiry, as a server that keeps it would.
+	srv.mu.Lock()
+	srv.sessionLocked(resp.ID).revoked = true
+	srv.mu.Unlock()
+
+	setListFilters(t, "", "", "", "", false)
+	if err := runList(sessionListCmd, nil); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var list listView[sessionView]
+	decodeOutput(t, buf, &list)
+	if len(list.Items) != 1 {
+		t.Fatalf("listed %d sessions, want 1", len(list.Items))
+	}
+	s := list.Items[0]
+	if !s.ExpiresAt.After(time.Now()) {
+		t.Fatalf("session expires at %v, want a future expiry", s.ExpiresAt)
+	}
+	if s.Status != "revoked" {
+		t.Errorf("revoked session listed as %q, want revoked", s.Status)
+	}
+	if got := sessionStatusColor(s.Status, s.ExpiresAt); got != colorRed {
+		t.Errorf("status color = %q, want red", got)
+	}
+}
+
+func TestRunListStatusFromFilter(t *testing.T) {
+	resetConfigGlobals(t)
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A server that filters by status but doesn't report it.
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"sessions":[{"id":"sess_1","level":"debug","expires_at":%q}]}`, expires)
+	}))
+	t.Cleanup(ts.Close)
+	apiEndpoint, apiToken, orgID, env = ts.URL, "dev-token", "org_dev", "dev"
+	buf := captureOutput(t, outputJSON)
+
+	for _, tt := range []struct{ status, want string }{
+		{"revoked", "revoked"},
+		{"", "active"},
+	} {
+		setListFilters(t, tt.status, "", "", "", false)
+		if err := runList(sessionListCmd, nil); err != nil {
+			t.Fatalf("list --status %q: %v", tt.status, err)
+		}
+		var list listView[sessionView]
+		decodeOutput(t, buf, &list)
+		if len(list.Items) != 1 || list.Items[0].Status != tt.want {
+			t.Errorf("list --status %q = %+v, want one %s session", tt.status, list.Items, tt.want)
+		}
+	}
+}
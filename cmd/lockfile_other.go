@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || windows)
+
+package cmd
+
+import "os"
+
+// tryLockFile cannot lock files on this platform. Concurrent refreshes are
+// not serialized, so a provider that rotates refresh tokens may ask for a
+// new login.
+func tryLockFile(f *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
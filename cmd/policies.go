@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -36,7 +36,7 @@ func runPoliciesGet(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := withTimeout(cmd.Context())
 	defer cancel()
 
 	policy, err := client.GetPolicy(ctx)
@@ -44,27 +44,47 @@ func runPoliciesGet(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get policy: %w", err)
 	}
 
-	fmt.Printf("Policy for %s/%s\n", orgID, env)
-	fmt.Println("------------------------------------------")
-	fmt.Printf("  Max TTL:              %s\n", formatDuration(policy.MaxTTLSeconds))
-	fmt.Printf("  Require Reason:       %v\n", policy.RequireReason)
-	fmt.Printf("  Allow Empty Selector: %v\n", policy.AllowEmptySelector)
+	return printItem(policyView{
+		Org:                 orgID,
+		Env:                 env,
+		MaxTTLSeconds:       policy.MaxTTLSeconds,
+		RequireReason:       policy.RequireReason,
+		AllowEmptySelector:  policy.AllowEmptySelector,
+		AllowedSelectorKeys: policy.AllowedSelectorKeys,
+		DefaultCaps:         capsView(policy.DefaultCaps),
+	}, writePolicyDetails)
+}
+
+type policyView struct {
+	Org                 string   `json:"org" yaml:"org"`
+	Env                 string   `json:"env" yaml:"env"`
+	MaxTTLSeconds       int      `json:"max_ttl_seconds" yaml:"max_ttl_seconds"`
+	RequireReason       bool     `json:"require_reason" yaml:"require_reason"`
+	AllowEmptySelector  bool     `json:"allow_empty_selector" yaml:"allow_empty_selector"`
+	AllowedSelectorKeys []string `json:"allowed_selector_keys,omitempty" yaml:"allowed_selector_keys,omitempty"`
+	DefaultCaps         capsView `json:"default_caps" yaml:"default_caps"`
+}
 
-	if len(policy.AllowedSelectorKeys) > 0 {
-		fmt.Printf("  Allowed Selector Keys: %v\n", policy.AllowedSelectorKeys)
+func writePolicyDetails(w io.Writer, p policyView) {
+	fmt.Fprintf(w, "Policy for %s/%s\n", p.Org, p.Env)
+	fmt.Fprintln(w, "------------------------------------------")
+	fmt.Fprintf(w, "  Max TTL:              %s\n", formatDuration(p.MaxTTLSeconds))
+	fmt.Fprintf(w, "  Require Reason:       %v\n", p.RequireReason)
+	fmt.Fprintf(w, "  Allow Empty Selector: %v\n", p.AllowEmptySelector)
+
+	if len(p.AllowedSelectorKeys) > 0 {
+		fmt.Fprintf(w, "  Allowed Selector Keys: %v\n", p.AllowedSelectorKeys)
 	}
 
-	if policy.DefaultCaps.MaxDebugEventsPerRequest > 0 || policy.DefaultCaps.MaxDebugEventsPerSession > 0 {
-		fmt.Printf("  Default Caps:\n")
-		if policy.DefaultCaps.MaxDebugEventsPerRequest > 0 {
-			fmt.Printf("    Max Events/Request: %d\n", policy.DefaultCaps.MaxDebugEventsPerRequest)
+	if p.DefaultCaps.MaxDebugEventsPerRequest > 0 || p.DefaultCaps.MaxDebugEventsPerSession > 0 {
+		fmt.Fprintf(w, "  Default Caps:\n")
+		if p.DefaultCaps.MaxDebugEventsPerRequest > 0 {
+			fmt.Fprintf(w, "    Max Events/Request: %d\n", p.DefaultCaps.MaxDebugEventsPerRequest)
 		}
-		if policy.DefaultCaps.MaxDebugEventsPerSession > 0 {
-			fmt.Printf("    Max Events/Session: %d\n", policy.DefaultCaps.MaxDebugEventsPerSession)
+		if p.DefaultCaps.MaxDebugEventsPerSession > 0 {
+			fmt.Fprintf(w, "    Max Events/Session: %d\n", p.DefaultCaps.MaxDebugEventsPerSession)
 		}
 	}
-
-	return nil
 }
 
 func formatDuration(seconds int) string {
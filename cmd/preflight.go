@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+)
+
+// policyViolation is one reason a create request would be rejected, with a
+// suggested fix.
+type policyViolation struct {
+	Problem string
+	Fix     string
+	// Usage marks a check that does not depend on the policy.
+	Usage bool
+}
+
+// preflight fetches the policy and reports the violations check finds,
+// together with local ones, which don't depend on the policy. If the policy
+// cannot be read because of an API error, local violations are still
+// reported and the server decides the rest, unless strict is set.
+func preflight(ctx context.Context, client *trek.Client, strict bool, local []policyViolation, check func(trek.Policy) []policyViolation) error {
+	policy, err := client.GetPolicy(ctx)
+	var apiErr *trek.APIError
+	switch {
+	case err == nil:
+		return violationsError(append(local, check(*policy)...))
+	case len(local) > 0:
+		return violationsError(local)
+	case !strict && errors.As(err, &apiErr):
+		// Tokens without access to the policy can still change sessions;
+		// the server enforces the policy either way.
+		fmt.Fprintf(os.Stderr, "Warning: could not check the policy (%v); sending the request anyway\n", err)
+		return nil
+	default:
+		return fmt.Errorf("failed to get policy: %w", err)
+	}
+}
+
+// checkCreateUsage returns the mistakes in req that any policy rejects.
+func checkCreateUsage(req trek.CreateSessionRequest) []policyViolation {
+	var violations []policyViolation
+	if req.Level != trek.LevelDebug && req.Level != trek.LevelTrace {
+		violations = append(violations, policyViolation{
+			Problem: fmt.Sprintf("level must be debug or trace, got %q", req.Level),
+			Fix:     "use --level debug or --level trace",
+			Usage:   true,
+		})
+	}
+	if req.TTLSeconds <= 0 {
+		violations = append(violations, policyViolation{
+			Problem: "ttl must be positive",
+			Fix:     "use --ttl 15m",
+			Usage:   true,
+		})
+	}
+	if req.Caps != nil {
+		violations = append(violations, negativeCapsViolations(*req.Caps)...)
+	}
+	return violations
+}
+
+// checkCreateRequest validates req against policy locally and returns every
+// violation, so they can all be reported at once.
+func checkCreateRequest(req trek.CreateSessionRequest, policy trek.Policy) []policyViolation {
+	var violations []policyViolation
+
+	if policy.MaxTTLSeconds > 0 && req.TTLSeconds > policy.MaxTTLSeconds {
+		violations = append(violations, policyViolation{
+			Problem: fmt.Sprintf("max TTL is %s", formatDuration(policy.MaxTTLSeconds)),
+			Fix:     "use --ttl " + ttlFlagValue(policy.MaxTTLSeconds),
+		})
+	}
+	if policy.RequireReason && strings.TrimSpace(req.Reason) == "" {
+		violations = append(violations, policyViolation{
+			Problem: "policy requires a reason",
+			Fix:     `add --reason "<why you are debugging>"`,
+		})
+	}
+	if trek.IsEmptySelector(req.Selector) && !policy.AllowEmptySelector {
+		violations = append(violations, policyViolation{
+			Problem: "at least one selector field required, the policy does not allow an empty selector",
+			Fix:     "add --user, --request, --tenant, --route or --selector",
+		})
+	}
+	if req.Caps != nil {
+		violations = append(violations, capsViolations(*req.Caps, policy.DefaultCaps)...)
+	}
+	if len(policy.AllowedSelectorKeys) > 0 {
+		for _, key := range selectorKeys(req.Selector) {
+			if !slices.Contains(policy.AllowedSelectorKeys, key) {
+				violations = append(violations, policyViolation{
+					Problem: fmt.Sprintf("selector key %q is not allowed by policy", key),
+					Fix:     "allowed keys: " + strings.Join(policy.AllowedSelectorKeys, ", "),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// capsFlags names the flag for each cap, in the order they are checked.
+func capsFlags(caps trek.Caps) []struct {
+	flag  string
+	value int
+} {
+	return []struct {
+		flag  string
+		value int
+	}{
+		{"--max-events-per-request", caps.MaxDebugEventsPerRequest},
+		{"--max-events-per-session", caps.MaxDebugEventsPerSession},
+	}
+}
+
+// negativeCapsViolations rejects negative caps, whatever the policy.
+func negativeCapsViolations(caps trek.Caps) []policyViolation {
+	var violations []policyViolation
+	for _, c := range capsFlags(caps) {
+		if c.value < 0 {
+			violations = append(violations, policyViolation{
+				Problem: fmt.Sprintf("%s must not be negative", c.flag),
+				Fix:     fmt.Sprintf("use a positive %s, or leave it out for the policy default", c.flag),
+				Usage:   true,
+			})
+		}
+	}
+	return violations
+}
+
+// capsViolations checks caps against the policy's default caps, which are the
+// most a session may ask for.
+func capsViolations(caps, limits trek.Caps) []policyViolation {
+	var violations []policyViolation
+	for i, c := range capsFlags(caps) {
+		limit := capsFlags(limits)[i].value
+		if limit > 0 && c.value > limit {
+			violations = append(violations, policyViolation{
+				Problem: fmt.Sprintf("policy allows at most %d for %s", limit, c.flag),
+				Fix:     fmt.Sprintf("use %s %d", c.flag, limit),
+			})
+		}
+	}
+	return violations
+}
+
+// violationsError turns violations into one error listing them all. It is a
+// policy error unless every violation is a plain usage mistake.
+func violationsError(violations []policyViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	code, exit := codeUsage, exitUsage
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		if !v.Usage {
+			code, exit = codePolicy, exitPolicy
+		}
+		lines[i] = fmt.Sprintf("  - %s: %s", v.Problem, v.Fix)
+	}
+	return &cliError{
+		Code:     code,
+		ExitCode: exit,
+		Err:      fmt.Errorf("session would be rejected:\n%s", strings.Join(lines, "\n")),
+	}
+}
+
+// ttlFlagValue formats seconds the way --ttl accepts it, e.g. 30m or 1h30m.
+func ttlFlagValue(seconds int) string {
+	s := (time.Duration(seconds) * time.Second).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
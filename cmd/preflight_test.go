@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+)
+
+func TestCheckCreateRequest(t *testing.T) {
+	valid := trek.CreateSessionRequest{
+		Selector:   trek.Selector{UserID: "u1", Custom: map[string]string{"region": "eu"}},
+		Level:      trek.LevelDebug,
+		TTLSeconds: 600,
+		Reason:     "bug",
+	}
+	policy := trek.Policy{
+		MaxTTLSeconds:       1800,
+		RequireReason:       true,
+		AllowedSelectorKeys: []string{"user_id", "region"},
+		DefaultCaps:         trek.Caps{MaxDebugEventsPerRequest: 100},
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*trek.CreateSessionRequest)
+		policy trek.Policy
+		want   []string
+	}{
+		{name: "valid", policy: policy},
+		{name: "no policy", modify: func(r *trek.CreateSessionRequest) { r.TTLSeconds, r.Reason = 86400, "" }},
+		{name: "ttl over max", modify: func(r *trek.CreateSessionRequest) { r.TTLSeconds = 3600 }, policy: policy, want: []string{"max TTL is 30 minutes: use --ttl 30m"}},
+		{name: "blank reason", modify: func(r *trek.CreateSessionRequest) { r.Reason = "  " }, policy: policy, want: []string{"policy requires a reason: add --reason"}},
+		{name: "empty selector", modify: func(r *trek.CreateSessionRequest) { r.Selector = trek.Selector{} }, policy: trek.Policy{}, want: []string{"empty selector"}},
+		{name: "empty selector allowed", modify: func(r *trek.CreateSessionRequest) { r.Selector = trek.Selector{} }, policy: trek.Policy{AllowEmptySelector: true}},
+		{name: "caps within policy", modify: func(r *trek.CreateSessionRequest) {
+			r.Caps = &trek.Caps{MaxDebugEventsPerRequest: 50, MaxDebugEventsPerSession: 99999}
+		}, policy: policy},
+		{name: "caps over policy", modify: func(r *trek.CreateSessionRequest) { r.Caps = &trek.Caps{MaxDebugEventsPerRequest: 500} }, policy: policy, want: []string{"policy allows at most 100 for --max-events-per-request: use --max-events-per-request 100"}},
+		{
+			name:   "several",
+			modify: func(r *trek.CreateSessionRequest) { r.TTLSeconds, r.Reason, r.Selector.Route = 7200, "", "/api/*" },
+			policy: policy,
+			want:   []string{"max TTL", "requires a reason", `selector key "route" is not allowed by policy: allowed keys: user_id, region`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			req.Selector.Custom = map[string]string{"region": "eu"}
+			if tt.modify != nil {
+				tt.modify(&req)
+			}
+			violations := checkCreateRequest(req, tt.policy)
+			if len(violations) != len(tt.want) {
+				t.Fatalf("got %d violations %+v, want %d", len(violations), violations, len(tt.want))
+			}
+			msg := violationsError(violations)
+			for _, want := range tt.want {
+				if !contains(msg.Error(), want) {
+					t.Errorf("violations %q do not mention %q", msg, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckCreateUsage(t *testing.T) {
+	valid := trek.CreateSessionRequest{
+		Selector:   trek.Selector{UserID: "u1"},
+		Level:      trek.LevelDebug,
+		TTLSeconds: 600,
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*trek.CreateSessionRequest)
+		want   []string
+	}{
+		{name: "valid"},
+		{name: "empty selector", modify: func(r *trek.CreateSessionRequest) { r.Selector = trek.Selector{} }},
+		{name: "zero ttl", modify: func(r *trek.CreateSessionRequest) { r.TTLSeconds = 0 }, want: []string{"ttl must be positive"}},
+		{name: "bad level", modify: func(r *trek.CreateSessionRequest) { r.Level = "info" }, want: []string{`level must be debug or trace, got "info"`}},
+		{name: "negative caps", modify: func(r *trek.CreateSessionRequest) { r.Caps = &trek.Caps{MaxDebugEventsPerSession: -1} }, want: []string{"--max-events-per-session must not be negative"}},
+		{name: "zero caps", modify: func(r *trek.CreateSessionRequest) { r.Caps = &trek.Caps{} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			if tt.modify != nil {
+				tt.modify(&req)
+			}
+			violations := checkCreateUsage(req)
+			if len(violations) != len(tt.want) {
+				t.Fatalf("got %d violations %+v, want %d", len(violations), violations, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !violations[i].Usage || !contains(violations[i].Problem, want) {
+					t.Errorf("violation %+v, want a usage violation mentioning %q", violations[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestViolationsErrorExitCode(t *testing.T) {
+	if err := violationsError(nil); err != nil {
+		t.Errorf("violationsError(nil) = %v, want nil", err)
+	}
+
+	usage := policyViolation{Problem: "ttl must be positive", Fix: "use --ttl 15m", Usage: true}
+	policy := policyViolation{Problem: "policy requires a reason", Fix: "add --reason"}
+
+	if got := classifyError(violationsError([]policyViolation{usage})); got.ExitCode != exitUsage {
+		t.Errorf("usage-only exit code = %d, want %d", got.ExitCode, exitUsage)
+	}
+	if got := classifyError(violationsError([]policyViolation{usage, policy})); got.ExitCode != exitPolicy {
+		t.Errorf("mixed exit code = %d, want %d", got.ExitCode, exitPolicy)
+	}
+}
+
+func TestTTLFlagValue(t *testing.T) {
+	tests := map[int]string{
+		30:    "30s",
+		90:    "1m30s",
+		1800:  "30m",
+		3600:  "1h",
+		5400:  "1h30m",
+		86400: "24h",
+	}
+	for seconds, want := range tests {
+		if got := ttlFlagValue(seconds); got != want {
+			t.Errorf("ttlFlagValue(%d) = %q, want %q", seconds, got, want)
+		}
+		if d, err := time.ParseDuration(want); err != nil || int(d.Seconds()) != seconds {
+			t.Errorf("%q does not parse back to %ds", want, seconds)
+		}
+	}
+}
+
+func TestRunCreatePreflight(t *testing.T) {
+	srv := startDevServer(t, trek.Policy{MaxTTLSeconds: 1800, RequireReason: true})
+	buf := captureOutput(t, outputJSON)
+	setCreateFlags(t, "u1", time.Hour, "")
+
+	err := runCreate(sessionCreateCmd, nil)
+	if got := classifyError(err); got.ExitCode != exitPolicy {
+		t.Fatalf("create = %v, want a policy error", err)
+	}
+	if !contains(err.Error(), "use --ttl 30m") || !contains(err.Error(), "add --reason") {
+		t.Errorf("error %q does not list both violations", err)
+	}
+	if len(srv.sessions) != 0 {
+		t.Fatal("a session was created despite the violations")
+	}
+
+	createDryRun = true
+	defer func() { createDryRun = false }()
+	ttl, reason = 20*time.Minute, "checkout bug"
+	if err := runCreate(sessionCreateCmd, nil); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	var req createRequestView
+	decodeOutput(t, buf, &req)
+	if req.TTLSeconds != 1200 || req.Selector.UserID != "u1" || req.Reason != "checkout bug" || req.Level != "debug" {
+		t.Errorf("dry run request = %+v", req)
+	}
+	if len(srv.sessions) != 0 {
+		t.Error("dry run created a session")
+	}
+}
+
+func TestRunCreateDryRunHuman(t *testing.T) {
+	startDevServer(t, trek.Policy{})
+	buf := captureOutput(t, outputTable)
+	setCreateFlags(t, "u1", 10*time.Minute, "")
+	createDryRun = true
+	defer func() { createDryRun = false }()
+
+	if err := runCreate(sessionCreateCmd, nil); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"ttl_seconds": 600`) {
+		t.Errorf("dry run output does not show the request:\n%s", buf)
+	}
+}
+
+func TestSessionCaps(t *testing.T) {
+	srv := startDevServer(t, trek.Policy{DefaultCaps: trek.Caps{MaxDebugEventsPerRequest: 100, MaxDebugEventsPerSession: 1000}})
+	buf := captureOutput(t, outputJSON)
+	setCreateFlags(t, "u1", 10*time.Minute, "")
+	defer func() { maxPerRequest, maxPerSession = 0, 0 }()
+	defer func() { extendMaxPerRequest, extendMaxPerSession = 0, 0 }()
+
+	maxPerRequest = 20
+	if err := runCreate(sessionCreateCmd, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	var created createdSessionView
+	decodeOutput(t, buf, &created)
+	if got := srv.sessions[0].Caps; got != (trek.Caps{MaxDebugEventsPerRequest: 20, MaxDebugEventsPerSession: 1000}) {
+		t.Errorf("created caps = %+v, want 20 per request and the policy default per session", got)
+	}
+
+	extendMaxPerSession = 500
+	if err := runExtend(sessionExtendCmd, []string{created.ID}); err != nil {
+		t.Fatalf("extend caps: %v", err)
+	}
+	var updated extendedSessionView
+	decodeOutput(t, buf, &updated)
+	if updated.Extended || !updated.ExpiresAt.Equal(created.ExpiresAt) {
+		t.Errorf("caps-only update changed the expiry: %+v", updated)
+	}
+	if updated.Caps == nil || *updated.Caps != (capsView{MaxDebugEventsPerRequest: 20, MaxDebugEventsPerSession: 500}) {
+		t.Errorf("updated caps = %+v, want 20 and 500", updated.Caps)
+	}
+
+	extendMaxPerSession = 5000
+	err := runExtend(sessionExtendCmd, []string{created.ID})
+	if got := classifyError(err); got.ExitCode != exitPolicy || !contains(err.Error(), "use --max-events-per-session 1000") {
+		t.Errorf("extend over the policy cap = %v, want a policy error", err)
+	}
+}
+
+func TestRunCreateEmptySelector(t *testing.T) {
+	srv := startDevServer(t, trek.Policy{})
+	captureOutput(t, outputJSON)
+	setCreateFlags(t, "", 10*time.Minute, "")
+
+	err := runCreate(sessionCreateCmd, nil)
+	if got := classifyError(err); got.ExitCode != exitPolicy || !contains(err.Error(), "at least one selector field required") {
+		t.Fatalf("create with an empty selector = %v, want a policy error", err)
+	}
+
+	srv.policy.AllowEmptySelector = true
+	if err := runCreate(sessionCreateCmd, nil); err != nil {
+		t.Fatalf("create with an empty selector the policy allows: %v", err)
+	}
+	if len(srv.sessions) != 1 {
+		t.Errorf("got %d sessions, want 1", len(srv.sessions))
+	}
+}
+
+func TestRunCreateNegativeCapsWithoutPolicy(t *testing.T) {
+	startDevServer(t, trek.Policy{})
+	// Nothing listens here, so the policy cannot be fetched.
+	apiEndpoint = "http://127.0.0.1:1"
+	captureOutput(t, outputJSON)
+	setCreateFlags(t, "u1", 10*time.Minute, "")
+	defer func() { maxPerRequest = 0 }()
+
+	maxPerRequest = -5
+	err := runCreate(sessionCreateCmd, nil)
+	if got := classifyError(err); got.ExitCode != exitUsage || !contains(err.Error(), "--max-events-per-request must not be negative") {
+		t.Errorf("create with negative caps = %v, want a usage error", err)
+	}
+}
+
+func TestExtendCapsAndTTL(t *testing.T) {
+	srv := startDevServer(t, trek.Policy{MaxTTLSeconds: 3600, DefaultCaps: trek.Caps{MaxDebugEventsPerRequest: 100}})
+	buf := captureOutput(t, outputJSON)
+	setCreateFlags(t, "u1", 50*time.Minute, "")
+	oldTTL := extendTTL
+	defer func() { extendTTL, extendMaxPerRequest, extendMaxPerSession = oldTTL, 0, 0 }()
+	sessionExtendCmd.Flags().Set("ttl", "15m")
+	defer func() { sessionExtendCmd.Flags().Lookup("ttl").Changed = false }()
+
+	if err := runCreate(sessionCreateCmd, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	var created createdSessionView
+	decodeOutput(t, buf, &created)
+
+	// A TTL over the policy is rejected before the caps are touched.
+	extendTTL, extendMaxPerRequest = 2*time.Hour, 50
+	err := runExtend(sessionExtendCmd, []string{created.ID})
+	if got := classifyError(err); got.ExitCode != exitPolicy || !contains(err.Error(), "use --ttl 1h") {
+		t.Fatalf("extend over the max TTL = %v, want a policy error", err)
+	}
+	if got := srv.sessions[0].Caps.MaxDebugEventsPerRequest; got != 100 {
+		t.Errorf("caps changed to %d by a rejected extend", got)
+	}
+
+	// Within the max TTL, but the server rejects the total: the caps change
+	// is reported and the command fails.
+	extendTTL = 30 * time.Minute
+	err = runExtend(sessionExtendCmd, []string{created.ID})
+	if err == nil || !contains(err.Error(), "session caps were updated, but extending the session failed") {
+		t.Fatalf("extend past the max TTL = %v, want a partial failure", err)
+	}
+	var partial extendedSessionView
+	decodeOutput(t, buf, &partial)
+	if partial.Extended || partial.Caps == nil || partial.Caps.MaxDebugEventsPerRequest != 50 {
+		t.Errorf("partial result = %+v, want updated caps and no extension", partial)
+	}
+}
@@ -0,0 +1,399 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats accepted by --output.
+const (
+	outputTable  = "table"
+	outputWide   = "wide"
+	outputJSON   = "json"
+	outputYAML   = "yaml"
+	outputNDJSON = "ndjson"
+
+	// Template formats take the template after '=' or from --template-file.
+	outputJSONPath   = "jsonpath"
+	outputGoTemplate = "go-template"
+)
+
+var outputFormats = []string{outputTable, outputWide, outputJSON, outputYAML, outputNDJSON,
+	outputJSONPath + "=...", outputGoTemplate + "=..."}
+
+// templateFile holds the jsonpath or go-template for --output.
+var templateFile string
+
+// stdout is where command output goes; tests replace it.
+var stdout io.Writer = os.Stdout
+
+// outputFormat splits --output into the format name and, for jsonpath and
+// go-template, the template text.
+func outputFormat() (format, text string, err error) {
+	format, text, hasText := strings.Cut(outputFmt, "=")
+	if format == "" {
+		format = outputTable
+	}
+	if format == outputTable && !hasText && templateFile != "" {
+		// --template-file on its own implies go-template.
+		format = outputGoTemplate
+	}
+
+	switch format {
+	case outputTable, outputWide, outputJSON, outputYAML, outputNDJSON:
+		if hasText {
+			return "", "", fmt.Errorf("output format %q does not take a template", format)
+		}
+		return format, "", nil
+	case outputJSONPath, outputGoTemplate:
+	default:
+		return "", "", fmt.Errorf("unknown output format %q: expected one of %s", outputFmt, strings.Join(outputFormats, ", "))
+	}
+
+	switch {
+	case hasText && templateFile != "":
+		return "", "", fmt.Errorf("give the %s template inline or with --template-file, not both", format)
+	case templateFile != "":
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read template file: %w", err)
+		}
+		text = string(data)
+	case text == "":
+		return "", "", fmt.Errorf("-o %s needs a template: -o %s=TEMPLATE or --template-file", format, format)
+	}
+	return format, text, nil
+}
+
+// validateOutputFormat rejects an unknown --output or a template that
+// doesn't parse before a command does any work.
+func validateOutputFormat(cmd *cobra.Command, args []string) error {
+	format, text, err := outputFormat()
+	if err != nil {
+		return err
+	}
+	switch format {
+	case outputJSONPath:
+		_, err = parseJSONPathTemplate(text)
+	case outputGoTemplate:
+		_, err = parseGoTemplate(text)
+	}
+	return err
+}
+
+func parseGoTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid go-template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// printTemplate renders a jsonpath template over the JSON form of jsonValue,
+// or a go-template over goValue, so templates see the same field names as
+// -o json and Go field names respectively.
+func printTemplate(format, text string, jsonValue, goValue any) error {
+	if format == outputJSONPath {
+		tmpl, err := parseJSONPathTemplate(text)
+		if err != nil {
+			return err
+		}
+		out, err := tmpl.execute(jsonValue)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(stdout, out)
+		return err
+	}
+
+	tmpl, err := parseGoTemplate(text)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(stdout, goValue); err != nil {
+		return fmt.Errorf("go-template failed: %w", err)
+	}
+	return nil
+}
+
+// humanOutput reports whether output is for people rather than programs.
+func humanOutput() bool {
+	format, _, err := outputFormat()
+	return err == nil && (format == outputTable || format == outputWide)
+}
+
+// column is one table column. Wide columns only appear with -o wide,
+// Truncate limits the cell width in the normal table, and Color picks an
+// ANSI color for the cell. Name is how --columns refers to the column.
+type column[T any] struct {
+	Name     string
+	Header   string
+	Value    func(T) string
+	Wide     bool
+	Truncate int
+	Color    func(T) string
+}
+
+// selectColumns returns the named columns in the given order, shown even
+// if they are normally wide-only.
+func selectColumns[T any](columns []column[T], names []string) ([]column[T], error) {
+	var selected []column[T]
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		i := slices.IndexFunc(columns, func(c column[T]) bool { return c.Name == name })
+		if i < 0 {
+			valid := make([]string, len(columns))
+			for j, c := range columns {
+				valid[j] = c.Name
+			}
+			return nil, fmt.Errorf("unknown column %q: expected one of %s", name, strings.Join(valid, ", "))
+		}
+		c := columns[i]
+		c.Wide = false
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+// identified is implemented by views with an ID, which --quiet prints
+// instead of the full output.
+type identified interface {
+	id() string
+}
+
+// listView is the JSON and YAML shape of every list, so lists can grow
+// fields without breaking consumers.
+type listView[T any] struct {
+	Items []T `json:"items" yaml:"items"`
+}
+
+// printList writes items as a table or in the machine-readable format
+// chosen with --output. empty is printed instead of an empty table.
+func printList[T any](items []T, columns []column[T], empty string) error {
+	if items == nil {
+		items = []T{}
+	}
+
+	format, text, err := outputFormat()
+	if err != nil {
+		return err
+	}
+	switch format {
+	case outputJSONPath, outputGoTemplate:
+		// Lists are {"items": [...]} to jsonpath, as in -o json, and the
+		// bare slice to go-template so {{range .}} works.
+		return printTemplate(format, text, listView[T]{Items: items}, items)
+	case outputJSON:
+		return writeJSON(listView[T]{Items: items})
+	case outputYAML:
+		return writeYAML(listView[T]{Items: items})
+	case outputNDJSON:
+		enc := json.NewEncoder(stdout)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return fmt.Errorf("failed to encode output: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if quietMode && printIDs(items) {
+		return nil
+	}
+	if len(items) == 0 {
+		fmt.Fprintln(stdout, empty)
+		return nil
+	}
+
+	wide := format == outputWide
+	var shown []column[T]
+	header := []string{}
+	for _, c := range columns {
+		if c.Wide && !wide {
+			continue
+		}
+		shown = append(shown, c)
+		header = append(header, c.Header)
+	}
+
+	rows := [][]string{header}
+	colors := [][]string{nil}
+	for _, item := range items {
+		cells := make([]string, len(shown))
+		cellColors := make([]string, len(shown))
+		for i, c := range shown {
+			cells[i] = c.Value(item)
+			if c.Color != nil {
+				cellColors[i] = c.Color(item)
+			}
+		}
+		rows = append(rows, cells)
+		colors = append(colors, cellColors)
+	}
+
+	if !wide {
+		limits := columnLimits(shown, rows, terminalWidth())
+		for _, row := range rows[1:] {
+			for i := range row {
+				if limits[i] > 0 {
+					row[i] = truncate(row[i], limits[i])
+				}
+			}
+		}
+	}
+	return writeTable(stdout, rows, colors)
+}
+
+// terminalWidth is the width to fit tables into: COLUMNS if set, else the
+// terminal's width, else 0 when output is not a terminal.
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	if f, ok := stdout.(*os.File); ok && isTerminal(f) {
+		return terminalSize(f)
+	}
+	return 0
+}
+
+// columnLimits returns the maximum width of each column, 0 meaning no limit.
+// Without a known width, columns are cut at their Truncate width. With one,
+// nothing is cut if the table fits; otherwise the widest truncatable column
+// is narrowed first, down to its Truncate width, so long selectors stay
+// readable on wide terminals.
+func columnLimits[T any](columns []column[T], rows [][]string, width int) []int {
+	limits := make([]int, len(columns))
+	if width <= 0 {
+		for i, c := range columns {
+			limits[i] = c.Truncate
+		}
+		return limits
+	}
+
+	widths := make([]int, len(columns))
+	total := 2 * (len(columns) - 1)
+	for i := range columns {
+		for _, row := range rows {
+			widths[i] = max(widths[i], utf8.RuneCountInString(row[i]))
+		}
+		total += widths[i]
+	}
+
+	for total > width {
+		widest := -1
+		for i, c := range columns {
+			if c.Truncate > 0 && widths[i] > c.Truncate && (widest < 0 || widths[i] > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		widths[widest]--
+		limits[widest] = widths[widest]
+		total--
+	}
+	return limits
+}
+
+// writeTable aligns rows into columns two spaces apart, then colors cells.
+// Padding is computed on the plain text so colored and plain output line up
+// identically.
+func writeTable(w io.Writer, rows, colors [][]string) error {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	color := colorEnabled()
+	var b strings.Builder
+	for r, row := range rows {
+		for i, cell := range row {
+			text := cell
+			if color && colors[r] != nil && colors[r][i] != "" {
+				text = colors[r][i] + cell + colorReset
+			}
+			b.WriteString(text)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2))
+			}
+		}
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// printItem writes a single resource. detail renders the human-readable
+// form used for table and wide output.
+func printItem[T any](item T, detail func(io.Writer, T)) error {
+	format, text, err := outputFormat()
+	if err != nil {
+		return err
+	}
+	switch format {
+	case outputJSONPath, outputGoTemplate:
+		return printTemplate(format, text, item, item)
+	case outputJSON:
+		return writeJSON(item)
+	case outputYAML:
+		return writeYAML(item)
+	case outputNDJSON:
+		if err := json.NewEncoder(stdout).Encode(item); err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+		return nil
+	}
+
+	if quietMode && printIDs([]T{item}) {
+		return nil
+	}
+	detail(stdout, item)
+	return nil
+}
+
+// printIDs prints the ID of each item and reports whether the items have
+// IDs at all.
+func printIDs[T any](items []T) bool {
+	var zero T
+	if _, ok := any(zero).(identified); !ok {
+		return false
+	}
+	for _, item := range items {
+		fmt.Fprintln(stdout, any(item).(identified).id())
+	}
+	return true
+}
+
+func writeJSON(v any) error {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	return nil
+}
+
+func writeYAML(v any) error {
+	enc := yaml.NewEncoder(stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	return enc.Close()
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+)
+
+// captureOutput sets the output format and collects what the printer
+// writes.
+func captureOutput(t *testing.T, format string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	oldOut, oldFmt, oldQuiet := stdout, outputFmt, quietMode
+	t.Cleanup(func() { stdout, outputFmt, quietMode = oldOut, oldFmt, oldQuiet })
+	stdout, outputFmt, quietMode = &buf, format, false
+	return &buf
+}
+
+func testSessions() []trek.Session {
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	return []trek.Session{
+		{
+			ID:        "sess_1",
+			Selector:  trek.Selector{UserID: "u123"},
+			Level:     trek.LevelDebug,
+			ExpiresAt: expires,
+			Labels:    map[string]string{"ticket": "TREK-1"},
+		},
+		{
+			ID:        "sess_2",
+			Selector:  trek.Selector{Route: "/api/orders*"},
+			Level:     trek.LevelTrace,
+			ExpiresAt: expires,
+		},
+	}
+}
+
+func TestPrintSessionsFormats(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    []string
+		notWant []string
+	}{
+		{format: outputTable, want: []string{"ID", "SELECTOR", "sess_1", "user:u123"}, notWant: []string{"LABELS"}},
+		{format: outputWide, want: []string{"LABELS", "ticket=TREK-1"}},
+		{format: outputJSON, want: []string{`"items": [`, `"id": "sess_1"`, `"user_id": "u123"`, `"status": "active"`}},
+		{format: outputYAML, want: []string{"items:", "id: sess_1", "user_id: u123"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			buf := captureOutput(t, tt.format)
+			if err := printSessions(testSessions()); err != nil {
+				t.Fatalf("printSessions() error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, buf)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(buf.String(), notWant) {
+					t.Errorf("output contains %q:\n%s", notWant, buf)
+				}
+			}
+		})
+	}
+}
+
+func TestPrintSessionsNDJSON(t *testing.T) {
+	buf := captureOutput(t, outputNDJSON)
+	if err := printSessions(testSessions()); err != nil {
+		t.Fatalf("printSessions() error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf)
+	}
+	var got sessionView
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil {
+		t.Fatalf("line is not JSON: %v", err)
+	}
+	if got.ID != "sess_2" || got.Selector.Route != "/api/orders*" {
+		t.Errorf("decoded %+v, want sess_2", got)
+	}
+}
+
+func TestPrintListEmpty(t *testing.T) {
+	buf := captureOutput(t, outputJSON)
+	if err := printSessions(nil); err != nil {
+		t.Fatalf("printSessions() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"items": []`) {
+		t.Errorf("empty list = %s, want empty items array", buf)
+	}
+
+	buf = captureOutput(t, outputTable)
+	printSessions(nil)
+	if strings.TrimSpace(buf.String()) != "No sessions found" {
+		t.Errorf("empty table = %q, want message", buf)
+	}
+}
+
+func TestPrintQuiet(t *testing.T) {
+	buf := captureOutput(t, outputTable)
+	quietMode = true
+
+	printSessions(testSessions())
+	if buf.String() != "sess_1\nsess_2\n" {
+		t.Errorf("quiet list = %q, want IDs only", buf)
+	}
+
+	// Views without an ID print in full.
+	buf.Reset()
+	printItem(policyView{Org: "org_1"}, func(w io.Writer, p policyView) { io.WriteString(w, "policy "+p.Org) })
+	if buf.String() != "policy org_1" {
+		t.Errorf("quiet item = %q, want full output", buf)
+	}
+}
+
+func TestValidateOutputFormat(t *testing.T) {
+	captureOutput(t, "")
+	for _, format := range outputFormats {
+		outputFmt = format
+		if err := validateOutputFormat(nil, nil); err != nil {
+			t.Errorf("validateOutputFormat(%q) error: %v", format, err)
+		}
+	}
+
+	outputFmt = "xml"
+	if err := validateOutputFormat(nil, nil); err == nil || !contains(err.Error(), "ndjson") {
+		t.Errorf("validateOutputFormat(xml) error = %v, want list of formats", err)
+	}
+}
+
+func TestPrintTemplates(t *testing.T) {
+	tmplFile := filepath.Join(t.TempDir(), "ids.tmpl")
+	os.WriteFile(tmplFile, []byte(`{{range .}}{{.ID}},{{end}}`), 0644)
+
+	tests := []struct {
+		name     string
+		format   string
+		file     string
+		wantList string
+		wantItem string
+	}{
+		{"jsonpath", "jsonpath={.items[*].id}", "", "sess_1 sess_2", ""},
+		{"jsonpath item", "jsonpath={.selector.user_id}", "", "", "u123"},
+		{"go-template", "go-template={{range .}}{{.ID}} {{end}}", "", "sess_1 sess_2 ", ""},
+		{"go-template item", "go-template={{.Level}}", "", "", "debug"},
+		{"template file", "go-template", tmplFile, "sess_1,sess_2,", ""},
+		{"template file alone", "table", tmplFile, "sess_1,sess_2,", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureOutput(t, tt.format)
+			templateFile = tt.file
+			defer func() { templateFile = "" }()
+
+			if tt.wantList != "" {
+				if err := printSessions(testSessions()); err != nil {
+					t.Fatalf("printSessions() error: %v", err)
+				}
+				if buf.String() != tt.wantList {
+					t.Errorf("list output = %q, want %q", buf, tt.wantList)
+				}
+			}
+			if tt.wantItem != "" {
+				if err := printItem(newSessionView(testSessions()[0]), writeSessionDetails); err != nil {
+					t.Fatalf("printItem() error: %v", err)
+				}
+				if buf.String() != tt.wantItem {
+					t.Errorf("item output = %q, want %q", buf, tt.wantItem)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateOutputTemplates(t *testing.T) {
+	captureOutput(t, "")
+	tests := []struct {
+		format  string
+		file    string
+		wantErr string
+	}{
+		{format: "jsonpath", wantErr: "needs a template"},
+		{format: "jsonpath={.items[", wantErr: "invalid jsonpath"},
+		{format: "go-template={{.ID", wantErr: "invalid go-template"},
+		{format: "json=x", wantErr: "does not take a template"},
+		{format: "go-template={{.ID}}", file: "ids.tmpl", wantErr: "not both"},
+		{format: "go-template", file: "/does/not/exist", wantErr: "failed to read template file"},
+	}
+	for _, tt := range tests {
+		outputFmt, templateFile = tt.format, tt.file
+		err := validateOutputFormat(nil, nil)
+		if err == nil || !contains(err.Error(), tt.wantErr) {
+			t.Errorf("validateOutputFormat(%q) error = %v, want %q", tt.format, err, tt.wantErr)
+		}
+	}
+	templateFile = ""
+}
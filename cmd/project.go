@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// projectFileName is the per-project config file, found by walking up from
+// the working directory.
+const projectFileName = ".trek.yaml"
+
+var (
+	// projectFile is the path of the discovered .trek.yaml, and project its
+	// contents; project is nil when there is none.
+	projectFile string
+	project     *projectConfig
+)
+
+// projectConfig is the layout of .trek.yaml. It is meant to be committed
+// with a service, so it holds defaults and never credentials.
+type projectConfig struct {
+	Service string            `yaml:"service,omitempty"`
+	Org     string            `yaml:"org,omitempty"`
+	Env     string            `yaml:"env,omitempty"`
+	Labels  map[string]string `yaml:"labels,omitempty"`
+	TTL     string            `yaml:"ttl,omitempty"`
+	Level   string            `yaml:"level,omitempty"`
+
+	Templates map[string]sessionTemplate `yaml:"templates,omitempty"`
+}
+
+// sessionTemplate is a named preset for 'trek session create --template'.
+type sessionTemplate struct {
+	User    string            `yaml:"user,omitempty"`
+	Request string            `yaml:"request,omitempty"`
+	Tenant  string            `yaml:"tenant,omitempty"`
+	Route   string            `yaml:"route,omitempty"`
+	Labels  map[string]string `yaml:"labels,omitempty"`
+	TTL     string            `yaml:"ttl,omitempty"`
+	Level   string            `yaml:"level,omitempty"`
+	Reason  string            `yaml:"reason,omitempty"`
+}
+
+// findProjectFile walks up from dir and returns the first .trek.yaml, or ""
+// if there is none.
+func findProjectFile(dir string) string {
+	for {
+		path := filepath.Join(dir, projectFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func readProjectFile(path string) (*projectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var cfg projectConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if _, err := parseProjectTTL(cfg.TTL); err != nil {
+		return nil, fmt.Errorf("invalid ttl in %s: %w", path, err)
+	}
+	for name, tmpl := range cfg.Templates {
+		if _, err := parseProjectTTL(tmpl.TTL); err != nil {
+			return nil, fmt.Errorf("invalid ttl in template %q in %s: %w", name, path, err)
+		}
+	}
+	return &cfg, nil
+}
+
+// loadProjectConfig discovers .trek.yaml from the working directory.
+// applyProjectConfig layers its values in once the active context is known.
+func loadProjectConfig() {
+	wd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	path := findProjectFile(wd)
+	if path == "" {
+		return
+	}
+
+	cfg, err := readProjectFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	projectFile, project = path, cfg
+}
+
+// applyProjectConfig fills org and env from the project file, beneath flags,
+// environment variables and the active context.
+func applyProjectConfig() {
+	if project == nil {
+		return
+	}
+	source := "project file " + projectFile
+	setFromConfig(&orgID, "org", project.Org, source)
+	setFromConfig(&env, "env", project.Env, source)
+}
+
+// template returns the named session template.
+func (p *projectConfig) template(name string) (*sessionTemplate, error) {
+	if p != nil {
+		if tmpl, ok := p.Templates[name]; ok {
+			return &tmpl, nil
+		}
+	}
+	if p == nil || len(p.Templates) == 0 {
+		return nil, fmt.Errorf("template %q not found: no templates defined in %s", name, projectFileName)
+	}
+	names := make([]string, 0, len(p.Templates))
+	for n := range p.Templates {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("template %q not found in %s (available: %v)", name, projectFile, names)
+}
+
+func parseProjectTTL(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	return d, nil
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testProjectConfig = `service: checkout
+org: org_project
+env: stage
+labels:
+  team: payments
+ttl: 30m
+level: trace
+templates:
+  slow-orders:
+    route: /api/orders*
+    ttl: 10m
+    reason: investigating slow orders
+    labels:
+      ticket: PAY-1
+`
+
+func TestFindProjectFile(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "services", "checkout", "internal")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := findProjectFile(nested); got != "" {
+		t.Errorf("findProjectFile() = %q, want none", got)
+	}
+
+	want := filepath.Join(root, "services", projectFileName)
+	if err := os.WriteFile(want, []byte("service: checkout\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := findProjectFile(nested); got != want {
+		t.Errorf("findProjectFile() = %q, want %q", got, want)
+	}
+}
+
+func TestReadProjectFileInvalidTTL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), projectFileName)
+	os.WriteFile(path, []byte("templates:\n  broken:\n    ttl: soon\n"), 0644)
+
+	_, err := readProjectFile(path)
+	if err == nil || !contains(err.Error(), `template "broken"`) {
+		t.Errorf("readProjectFile() error = %v, want invalid ttl in template", err)
+	}
+}
+
+func TestProjectConfigPrecedence(t *testing.T) {
+	resetConfigGlobals(t)
+	for _, name := range []string{"TREK_API_ENDPOINT", "TREK_API_TOKEN", "TREK_ORG_ID", "TREK_PROFILE",
+		"TREK_CREDENTIAL_STORE", "TREK_CREDENTIAL_HELPER"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("TREK_ENV", "prod")
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, projectFileName), []byte(testProjectConfig), 0644)
+	t.Chdir(dir)
+	cfgFile = writeTestConfig(t, "endpoint: https://trek.example.com\norg: org_user\nenv: dev\n")
+
+	initConfig()
+
+	if orgID != "org_project" {
+		t.Errorf("orgID = %q, want project value over user config", orgID)
+	}
+	if env != "prod" {
+		t.Errorf("env = %q, want TREK_ENV over project value", env)
+	}
+	if apiEndpoint != "https://trek.example.com" {
+		t.Errorf("apiEndpoint = %q, want user config value", apiEndpoint)
+	}
+	if want := "project file " + filepath.Join(dir, projectFileName); configSources["org"] != want {
+		t.Errorf("org source = %q, want %q", configSources["org"], want)
+	}
+}
+
+func TestProjectConfigBelowContext(t *testing.T) {
+	resetConfigGlobals(t)
+	for _, name := range []string{"TREK_API_ENDPOINT", "TREK_API_TOKEN", "TREK_ORG_ID", "TREK_ENV", "TREK_PROFILE",
+		"TREK_CREDENTIAL_STORE", "TREK_CREDENTIAL_HELPER"} {
+		t.Setenv(name, "")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, projectFileName), []byte(testProjectConfig), 0644)
+	t.Chdir(dir)
+	cfgFile = writeTestConfig(t, testContextsConfig)
+	profileName = "staging"
+
+	initConfig()
+
+	if orgID != "org_staging" || env != "stage" {
+		t.Errorf("org, env = %q, %q; want the selected context over the project file", orgID, env)
+	}
+	if configSources["org"] != "context staging" {
+		t.Errorf("org source = %q, want context staging", configSources["org"])
+	}
+}
+
+func TestApplySessionDefaults(t *testing.T) {
+	resetConfigGlobals(t)
+	path := filepath.Join(t.TempDir(), projectFileName)
+	os.WriteFile(path, []byte(testProjectConfig), 0644)
+	cfg, err := readProjectFile(path)
+	if err != nil {
+		t.Fatalf("readProjectFile() error: %v", err)
+	}
+	projectFile, project = path, cfg
+
+	reset := func() {
+		userID, requestID, tenantID, route, reason = "", "", "", "", ""
+		ttl, level, labels, sessionTemplateName = 15*time.Minute, "debug", nil, ""
+	}
+	t.Cleanup(reset)
+
+	t.Run("project defaults", func(t *testing.T) {
+		reset()
+		labels = []string{"team=search"}
+
+		got, err := applySessionDefaults(sessionCreateCmd)
+		if err != nil {
+			t.Fatalf("applySessionDefaults() error: %v", err)
+		}
+		if ttl != 30*time.Minute || level != "trace" {
+			t.Errorf("ttl, level = %v, %q; want 30m, trace", ttl, level)
+		}
+		if got["service"] != "checkout" || got["team"] != "search" {
+			t.Errorf("labels = %v, want service=checkout and --label overriding team", got)
+		}
+	})
+
+	t.Run("template", func(t *testing.T) {
+		reset()
+		sessionTemplateName = "slow-orders"
+
+		got, err := applySessionDefaults(sessionCreateCmd)
+		if err != nil {
+			t.Fatalf("applySessionDefaults() error: %v", err)
+		}
+		if route != "/api/orders*" || reason != "investigating slow orders" {
+			t.Errorf("route, reason = %q, %q; want template values", route, reason)
+		}
+		if ttl != 10*time.Minute {
+			t.Errorf("ttl = %v, want template ttl 10m", ttl)
+		}
+		if got["ticket"] != "PAY-1" || got["team"] != "payments" {
+			t.Errorf("labels = %v, want template and project labels", got)
+		}
+	})
+
+	t.Run("unknown template", func(t *testing.T) {
+		reset()
+		sessionTemplateName = "nope"
+
+		_, err := applySessionDefaults(sessionCreateCmd)
+		if err == nil || !contains(err.Error(), "slow-orders") {
+			t.Errorf("error = %v, want list of available templates", err)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+)
+
+// propagationPollInterval is how often --wait polls the active snapshot.
+var propagationPollInterval = time.Second
+
+// propagationProgressEvery is how often --wait reports that it is still
+// waiting.
+const propagationProgressEvery = 5 * time.Second
+
+// waitForPropagation polls the active-session snapshot SDKs for service are
+// served until it includes the session id, and returns how long that took.
+// The ETag of the last snapshot is passed back, so an unchanged snapshot
+// comes back not modified and counts as not live yet.
+func waitForPropagation(ctx context.Context, client *trek.Client, id, service string, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	human := humanOutput()
+	if human {
+		fmt.Fprintf(os.Stderr, "Waiting for session %s to go live...\n", id)
+	}
+
+	etag := ""
+	lastProgress := start
+	for {
+		reqCtx, reqCancel := withTimeout(ctx)
+		resp, err := client.GetActiveSessions(reqCtx, service, etag)
+		reqCancel()
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return 0, fmt.Errorf("session %s was not live after %s: %w", id, timeout, ctx.Err())
+			}
+			return 0, fmt.Errorf("failed to poll active sessions: %w", err)
+		}
+		if !resp.NotModified {
+			etag = resp.ETag
+			for _, s := range resp.Sessions {
+				if s.ID == id {
+					return time.Since(start), nil
+				}
+			}
+		}
+
+		if human && time.Since(lastProgress) >= propagationProgressEvery {
+			fmt.Fprintf(os.Stderr, "  still waiting (%s)\n", time.Since(start).Round(time.Second))
+			lastProgress = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return 0, fmt.Errorf("session %s was not live after %s: %w", id, timeout, ctx.Err())
+			}
+			return 0, ctx.Err()
+		case <-time.After(propagationPollInterval):
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+)
+
+// fakeSnapshot serves the active-session snapshot, adding the session after
+// a number of polls, and records the service of each poll.
+type fakeSnapshot struct {
+	mu        sync.Mutex
+	liveAfter int
+	services  []string
+}
+
+func (f *fakeSnapshot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.services = append(f.services, r.URL.Query().Get("service"))
+
+	resp := trek.ActiveSessionsResponse{Sessions: []trek.Session{{ID: "sess_other"}}}
+	if f.liveAfter > 0 && len(f.services) >= f.liveAfter {
+		resp.Sessions = append(resp.Sessions, trek.Session{ID: "sess_1"})
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func TestWaitForPropagation(t *testing.T) {
+	old := propagationPollInterval
+	propagationPollInterval = time.Millisecond
+	defer func() { propagationPollInterval = old }()
+	captureOutput(t, outputJSON)
+
+	t.Run("goes live", func(t *testing.T) {
+		snapshot := &fakeSnapshot{liveAfter: 3}
+		ts := httptest.NewServer(snapshot)
+		defer ts.Close()
+
+		_, err := waitForPropagation(t.Context(), trek.NewClient(ts.URL, "tok", "org", "dev"), "sess_1", "checkout", time.Second)
+		if err != nil {
+			t.Fatalf("waitForPropagation() error = %v", err)
+		}
+		if len(snapshot.services) != 3 || snapshot.services[0] != "checkout" {
+			t.Errorf("polled services %q, want checkout three times", snapshot.services)
+		}
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		ts := httptest.NewServer(&fakeSnapshot{})
+		defer ts.Close()
+
+		_, err := waitForPropagation(t.Context(), trek.NewClient(ts.URL, "tok", "org", "dev"), "sess_1", "cli", 50*time.Millisecond)
+		if got := classifyError(err); got.Code != codeTimeout || !contains(err.Error(), "sess_1 was not live after 50ms") {
+			t.Errorf("waitForPropagation() error = %v (%s), want a timeout", err, got.Code)
+		}
+	})
+}
+
+func TestWaitForPropagationSendsETag(t *testing.T) {
+	old := propagationPollInterval
+	propagationPollInterval = time.Millisecond
+	defer func() { propagationPollInterval = old }()
+	captureOutput(t, outputJSON)
+
+	// The session is added on the third poll; the second sees the same
+	// snapshot as the first.
+	srv := newDevServer(trek.Policy{}, "", nil)
+	var mu sync.Mutex
+	var sent []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent = append(sent, r.Header.Get("If-None-Match"))
+		if len(sent) == 3 {
+			srv.mu.Lock()
+			srv.sessions = append(srv.sessions, &devSession{Session: trek.Session{ID: "sess_1", ExpiresAt: time.Now().Add(time.Hour)}})
+			srv.version++
+			srv.mu.Unlock()
+		}
+		mu.Unlock()
+		srv.handler().ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	_, err := waitForPropagation(t.Context(), trek.NewClient(ts.URL, "tok", "org", "dev"), "sess_1", "cli", time.Second)
+	if err != nil {
+		t.Fatalf("waitForPropagation() error = %v", err)
+	}
+	want := []string{"", `"v0"`, `"v0"`}
+	if strings.Join(sent, ",") != strings.Join(want, ",") {
+		t.Errorf("polls sent If-None-Match %q, want %q", sent, want)
+	}
+}
+
+func TestRunCreateWait(t *testing.T) {
+	startDevServer(t, trek.Policy{})
+	buf := captureOutput(t, outputJSON)
+	setCreateFlags(t, "u1", 10*time.Minute, "")
+	createWait, waitTimeout = true, 5*time.Second
+	defer func() { createWait, waitTimeout = false, time.Minute }()
+	// cobra sets the context when it runs a command.
+	sessionCreateCmd.SetContext(context.Background())
+
+	if err := runCreate(sessionCreateCmd, nil); err != nil {
+		t.Fatalf("create --wait: %v", err)
+	}
+	var created createdSessionView
+	decodeOutput(t, buf, &created)
+	if !created.Live || created.ID == "" {
+		t.Errorf("created = %+v, want a live session", created)
+	}
+
+	waitTimeout = 0
+	if err := runCreate(sessionCreateCmd, nil); classifyError(err).ExitCode != exitUsage {
+		t.Errorf("create --wait-timeout 0 = %v, want a usage error", err)
+	}
+}
+
+func TestPropagationService(t *testing.T) {
+	defer func() { waitService = "" }()
+
+	tests := []struct {
+		name   string
+		flag   string
+		labels map[string]string
+		want   string
+	}{
+		{name: "default", want: "cli"},
+		{name: "service label", labels: map[string]string{"service": "checkout"}, want: "checkout"},
+		{name: "flag wins", flag: "billing", labels: map[string]string{"service": "checkout"}, want: "billing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waitService = tt.flag
+			if got := propagationService(tt.labels); got != tt.want {
+				t.Errorf("propagationService() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// oidcProvider holds the identity provider endpoints used by login, refresh
+// and logout. Field names follow the OIDC discovery document.
+type oidcProvider struct {
+	Name                        string `json:"-"`
+	Issuer                      string `json:"issuer"`
+	AuthorizationEndpoint       string `json:"authorization_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	RevocationEndpoint          string `json:"revocation_endpoint"`
+	JWKSURI                     string `json:"jwks_uri"`
+}
+
+// clerkProvider is the preset for Clerk, which serves its OAuth endpoints at
+// fixed paths under the instance domain.
+func clerkProvider(domain string) *oidcProvider {
+	base := "https://" + domain
+	return &oidcProvider{
+		Name:                        "clerk",
+		Issuer:                      base,
+		AuthorizationEndpoint:       base + "/oauth/authorize",
+		DeviceAuthorizationEndpoint: base + "/oauth/device/code",
+		TokenEndpoint:               base + "/oauth/token",
+		RevocationEndpoint:          base + "/oauth/token/revoke",
+		JWKSURI:                     base + "/.well-known/jwks.json",
+	}
+}
+
+// discoverProvider reads the issuer's .well-known/openid-configuration, which
+// works for any standards-compliant provider (Okta, Keycloak, Dex, ...).
+func discoverProvider(ctx context.Context, issuer string) (*oidcProvider, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+	endpoint := issuer + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("discovery request to %s failed (HTTP %d): %s", endpoint, resp.StatusCode, string(body))
+	}
+
+	var p oidcProvider
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid discovery document from %s: %w", endpoint, err)
+	}
+
+	if strings.TrimSuffix(p.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery document issuer %q does not match %q", p.Issuer, issuer)
+	}
+	if p.TokenEndpoint == "" {
+		return nil, fmt.Errorf("discovery document from %s has no token_endpoint", endpoint)
+	}
+
+	p.Name = "oidc"
+	return &p, nil
+}
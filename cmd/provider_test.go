@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// newDiscoveryServer serves an OIDC discovery document whose issuer is the
+// server's own URL. edit can adjust the document before it is served.
+func newDiscoveryServer(t *testing.T, edit func(doc map[string]string)) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		doc := map[string]string{
+			"issuer":                        srv.URL,
+			"authorization_endpoint":        srv.URL + "/auth",
+			"device_authorization_endpoint": srv.URL + "/device",
+			"token_endpoint":                srv.URL + "/token",
+			"revocation_endpoint":           srv.URL + "/revoke",
+			"jwks_uri":                      srv.URL + "/keys",
+		}
+		if edit != nil {
+			edit(doc)
+		}
+		json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDiscoverProvider(t *testing.T) {
+	srv := newDiscoveryServer(t, nil)
+
+	p, err := discoverProvider(context.Background(), srv.URL+"/")
+	if err != nil {
+		t.Fatalf("discoverProvider() error: %v", err)
+	}
+
+	if p.DeviceAuthorizationEndpoint != srv.URL+"/device" {
+		t.Errorf("DeviceAuthorizationEndpoint = %q", p.DeviceAuthorizationEndpoint)
+	}
+	if p.TokenEndpoint != srv.URL+"/token" {
+		t.Errorf("TokenEndpoint = %q", p.TokenEndpoint)
+	}
+	if p.RevocationEndpoint != srv.URL+"/revoke" {
+		t.Errorf("RevocationEndpoint = %q", p.RevocationEndpoint)
+	}
+	if p.JWKSURI != srv.URL+"/keys" {
+		t.Errorf("JWKSURI = %q", p.JWKSURI)
+	}
+}
+
+func TestDiscoverProvider_IssuerMismatch(t *testing.T) {
+	srv := newDiscoveryServer(t, func(doc map[string]string) {
+		doc["issuer"] = "https://evil.example.com"
+	})
+
+	_, err := discoverProvider(context.Background(), srv.URL)
+	if err == nil || !contains(err.Error(), "does not match") {
+		t.Errorf("error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestDiscoverProvider_MissingTokenEndpoint(t *testing.T) {
+	srv := newDiscoveryServer(t, func(doc map[string]string) {
+		delete(doc, "token_endpoint")
+	})
+
+	_, err := discoverProvider(context.Background(), srv.URL)
+	if err == nil || !contains(err.Error(), "token_endpoint") {
+		t.Errorf("error = %v, want missing token_endpoint", err)
+	}
+}
+
+func TestClerkProvider(t *testing.T) {
+	p := clerkProvider("clerk.example.com")
+
+	if p.DeviceAuthorizationEndpoint != "https://clerk.example.com/oauth/device/code" {
+		t.Errorf("DeviceAuthorizationEndpoint = %q", p.DeviceAuthorizationEndpoint)
+	}
+	if p.TokenEndpoint != "https://clerk.example.com/oauth/token" {
+		t.Errorf("TokenEndpoint = %q", p.TokenEndpoint)
+	}
+}
+
+func newLoginFlagsCmd(t *testing.T, flags map[string]string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{}
+	cmd.Flags().String("issuer", "", "")
+	cmd.Flags().String("clerk-domain", "", "")
+	cmd.Flags().String("client-id", "", "")
+	for k, v := range flags {
+		cmd.Flags().Set(k, v)
+	}
+	return cmd
+}
+
+func TestResolveLoginProvider_Issuer(t *testing.T) {
+	srv := newDiscoveryServer(t, nil)
+	cmd := newLoginFlagsCmd(t, map[string]string{"issuer": srv.URL, "client-id": "trek-cli"})
+
+	p, clientID, err := resolveLoginProvider(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("resolveLoginProvider() error: %v", err)
+	}
+	if p.Name != "oidc" {
+		t.Errorf("Name = %q, want oidc", p.Name)
+	}
+	if clientID != "trek-cli" {
+		t.Errorf("clientID = %q, want trek-cli", clientID)
+	}
+}
+
+func TestResolveLoginProvider_IssuerRequiresClientID(t *testing.T) {
+	t.Setenv("TREK_OIDC_CLIENT_ID", "")
+	cmd := newLoginFlagsCmd(t, map[string]string{"issuer": "https://idp.example.com"})
+
+	_, _, err := resolveLoginProvider(context.Background(), cmd)
+	if err == nil || !contains(err.Error(), "client-id is required") {
+		t.Errorf("error = %v, want client-id required", err)
+	}
+}
+
+func TestResolveLoginProvider_ClerkFromEnv(t *testing.T) {
+	t.Setenv("TREK_OIDC_ISSUER", "")
+	t.Setenv("TREK_CLERK_DOMAIN", "clerk.example.com")
+	t.Setenv("TREK_CLERK_CLIENT_ID", "abc")
+	cmd := newLoginFlagsCmd(t, nil)
+
+	p, clientID, err := resolveLoginProvider(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("resolveLoginProvider() error: %v", err)
+	}
+	if p.Name != "clerk" {
+		t.Errorf("Name = %q, want clerk", p.Name)
+	}
+	if clientID != "abc" {
+		t.Errorf("clientID = %q, want abc", clientID)
+	}
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// refreshSkew is how long before expiry stored credentials are refreshed,
+// so a token never expires in the middle of a command.
+const refreshSkew = 60 * time.Second
+
+// lockPollInterval is how often a process waiting for the credentials lock
+// tries again.
+const lockPollInterval = 50 * time.Millisecond
+
+var (
+	errCredentialsExpired = errors.New("stored credentials have expired, run 'trek auth login' to re-authenticate")
+	errRefreshRevoked     = errors.New("refresh token was revoked or has expired, run 'trek auth login' to re-authenticate")
+)
+
+// ensureFreshCredentials loads the stored credentials, refreshing them first
+// if the access token is expired or about to expire.
+//
+// The refresh runs under a lock on the credentials file. After acquiring it
+// the file is re-read, so when two trek processes race only one of them
+// talks to the token endpoint and the other picks up its result.
+func ensureFreshCredentials(ctx context.Context) (*StoredCredentials, error) {
+	creds, err := loadCredentials()
+	if err != nil {
+		return nil, err
+	}
+	if !needsRefresh(creds) {
+		return creds, nil
+	}
+	if creds.RefreshToken == "" || creds.TokenEndpoint == "" {
+		if time.Now().After(creds.ExpiresAt) {
+			return nil, errCredentialsExpired
+		}
+		return creds, nil
+	}
+
+	unlock, err := lockCredentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock credentials: %w", err)
+	}
+	defer unlock()
+
+	// Another process may have refreshed while we waited for the lock.
+	creds, err = loadCredentials()
+	if err != nil {
+		return nil, err
+	}
+	if !needsRefresh(creds) {
+		return creds, nil
+	}
+
+	refreshed, err := refreshAccessToken(ctx, *creds)
+	if err != nil {
+		if errors.Is(err, errRefreshRevoked) {
+			return nil, err
+		}
+		// A transient failure shouldn't lock the user out while the
+		// current token is still usable.
+		if time.Now().Before(creds.ExpiresAt) {
+			return creds, nil
+		}
+		return nil, fmt.Errorf("failed to refresh credentials: %w", err)
+	}
+
+	if err := saveCredentials(*refreshed); err != nil {
+		return nil, fmt.Errorf("failed to save refreshed credentials: %w", err)
+	}
+
+	return refreshed, nil
+}
+
+func needsRefresh(creds *StoredCredentials) bool {
+	return time.Now().Add(refreshSkew).After(creds.ExpiresAt)
+}
+
+// refreshAccessToken exchanges the refresh token for a new access token.
+// Providers that rotate refresh tokens return a new one, which replaces the
+// old; otherwise the existing refresh token is kept.
+func refreshAccessToken(ctx context.Context, creds StoredCredentials) (*StoredCredentials, error) {
+	data := url.Values{}
+	data.Set("grant_type", "refresh_token")
+	data.Set("refresh_token", creds.RefreshToken)
+	data.Set("client_id", creds.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, creds.TokenEndpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid token response (HTTP %d): %w", resp.StatusCode, err)
+	}
+
+	switch result.Error {
+	case "":
+	case "invalid_grant":
+		return nil, errRefreshRevoked
+	default:
+		return nil, fmt.Errorf("%s: %s", result.Error, result.ErrorDesc)
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("token response missing access_token (HTTP %d)", resp.StatusCode)
+	}
+
+	creds.AccessToken = result.AccessToken
+	creds.ExpiresAt = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	if result.RefreshToken != "" {
+		creds.RefreshToken = result.RefreshToken
+	}
+	if result.Scope != "" {
+		creds.Scopes = strings.Fields(result.Scope)
+	}
+
+	return &creds, nil
+}
+
+// lockCredentials takes an exclusive lock on a file next to the credentials
+// file and returns a function that releases it. The lock is held by the
+// operating system, so it goes away with the process that holds it and never
+// has to be taken over. Waiting for it is bounded by ctx.
+func lockCredentials(ctx context.Context) (func(), error) {
+	path := getCredentialsPath() + ".lock"
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if locked {
+			return func() {
+				unlockFile(f)
+				f.Close()
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, fmt.Errorf("timed out waiting for %s: %w", path, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenServer stands in for the identity provider's token endpoint.
+func newTokenServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEnsureFreshCredentials_NotExpiring(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var calls int32
+	srv := newTokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	})
+
+	saveCredentials(StoredCredentials{
+		AccessToken:   "current",
+		RefreshToken:  "refresh",
+		ExpiresAt:     time.Now().Add(time.Hour),
+		TokenEndpoint: srv.URL,
+	})
+
+	creds, err := ensureFreshCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.AccessToken != "current" {
+		t.Errorf("AccessToken = %q, want %q", creds.AccessToken, "current")
+	}
+	if calls != 0 {
+		t.Errorf("token endpoint called %d times, want 0", calls)
+	}
+}
+
+func TestEnsureFreshCredentials_RefreshesAndRotates(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	srv := newTokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if got := r.PostForm.Get("grant_type"); got != "refresh_token" {
+			t.Errorf("grant_type = %q, want refresh_token", got)
+		}
+		if got := r.PostForm.Get("refresh_token"); got != "old-refresh" {
+			t.Errorf("refresh_token = %q, want old-refresh", got)
+		}
+		if got := r.PostForm.Get("client_id"); got != "client-123" {
+			t.Errorf("client_id = %q, want client-123", got)
+		}
+		json.NewEncoder(w).Encode(TokenResponse{
+			AccessToken:  "new-access",
+			RefreshToken: "new-refresh",
+			ExpiresIn:    3600,
+		})
+	})
+
+	saveCredentials(StoredCredentials{
+		AccessToken:   "old-access",
+		RefreshToken:  "old-refresh",
+		ExpiresAt:     time.Now().Add(10 * time.Second),
+		ClientID:      "client-123",
+		TokenEndpoint: srv.URL,
+		Email:         "dev@example.com",
+	})
+
+	creds, err := ensureFreshCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.AccessToken != "new-access" {
+		t.Errorf("AccessToken = %q, want %q", creds.AccessToken, "new-access")
+	}
+
+	stored, err := loadCredentials()
+	if err != nil {
+		t.Fatalf("failed to reload credentials: %v", err)
+	}
+	if stored.RefreshToken != "new-refresh" {
+		t.Errorf("stored RefreshToken = %q, want rotated %q", stored.RefreshToken, "new-refresh")
+	}
+	if stored.Email != "dev@example.com" {
+		t.Errorf("stored Email = %q, want it preserved", stored.Email)
+	}
+	if time.Until(stored.ExpiresAt) < 50*time.Minute {
+		t.Errorf("stored ExpiresAt = %v, want about an hour from now", stored.ExpiresAt)
+	}
+}
+
+func TestEnsureFreshCredentials_KeepsRefreshTokenWithoutRotation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	srv := newTokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "new-access", ExpiresIn: 3600})
+	})
+
+	saveCredentials(StoredCredentials{
+		AccessToken:   "old-access",
+		RefreshToken:  "keep-me",
+		ExpiresAt:     time.Now().Add(-time.Minute),
+		TokenEndpoint: srv.URL,
+	})
+
+	if _, err := ensureFreshCredentials(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stored, _ := loadCredentials()
+	if stored.RefreshToken != "keep-me" {
+		t.Errorf("RefreshToken = %q, want %q", stored.RefreshToken, "keep-me")
+	}
+}
+
+func TestEnsureFreshCredentials_RevokedRefreshToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	srv := newTokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TokenResponse{Error: "invalid_grant", ErrorDesc: "refresh token revoked"})
+	})
+
+	saveCredentials(StoredCredentials{
+		AccessToken:   "old-access",
+		RefreshToken:  "revoked",
+		ExpiresAt:     time.Now().Add(10 * time.Second),
+		TokenEndpoint: srv.URL,
+	})
+
+	_, err := ensureFreshCredentials(context.Background())
+	if !errors.Is(err, errRefreshRevoked) {
+		t.Errorf("error = %v, want errRefreshRevoked", err)
+	}
+}
+
+func TestEnsureFreshCredentials_TransientFailureUsesValidToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	srv := newTokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	saveCredentials(StoredCredentials{
+		AccessToken:   "still-valid",
+		RefreshToken:  "refresh",
+		ExpiresAt:     time.Now().Add(30 * time.Second),
+		TokenEndpoint: srv.URL,
+	})
+
+	creds, err := ensureFreshCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.AccessToken != "still-valid" {
+		t.Errorf("AccessToken = %q, want %q", creds.AccessToken, "still-valid")
+	}
+}
+
+func TestEnsureFreshCredentials_ExpiredWithoutRefreshToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	saveCredentials(StoredCredentials{
+		AccessToken: "stale",
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	})
+
+	_, err := ensureFreshCredentials(context.Background())
+	if !errors.Is(err, errCredentialsExpired) {
+		t.Errorf("error = %v, want errCredentialsExpired", err)
+	}
+}
+
+func TestEnsureFreshCredentials_ConcurrentRefresh(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var calls int32
+	srv := newTokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		// Hold the lock long enough for the other callers to queue up.
+		time.Sleep(100 * time.Millisecond)
+		json.NewEncoder(w).Encode(TokenResponse{
+			AccessToken:  "new-access",
+			RefreshToken: "new-refresh",
+			ExpiresIn:    3600,
+		})
+	})
+
+	saveCredentials(StoredCredentials{
+		AccessToken:   "old-access",
+		RefreshToken:  "old-refresh",
+		ExpiresAt:     time.Now().Add(-time.Minute),
+		TokenEndpoint: srv.URL,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			creds, err := ensureFreshCredentials(context.Background())
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if creds.AccessToken != "new-access" {
+				t.Errorf("AccessToken = %q, want %q", creds.AccessToken, "new-access")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("token endpoint called %d times, want 1", calls)
+	}
+}
+
+func TestLockCredentials_IgnoresLeftoverLockFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	saveCredentials(StoredCredentials{AccessToken: "x"})
+	// A process that died while holding the lock leaves the file behind,
+	// but not the lock.
+	lockPath := getCredentialsPath() + ".lock"
+	if err := os.WriteFile(lockPath, []byte("999999\n"), 0600); err != nil {
+		t.Fatalf("failed to write lock: %v", err)
+	}
+
+	unlock, err := lockCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("lockCredentials() error: %v", err)
+	}
+	unlock()
+}
+
+func TestLockCredentials_WaitsForHolder(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	saveCredentials(StoredCredentials{AccessToken: "x"})
+
+	unlock, err := lockCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("lockCredentials() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := lockCredentials(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("lockCredentials() while held = %v, want a deadline error", err)
+	}
+
+	unlock()
+	unlockAgain, err := lockCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("lockCredentials() after release: %v", err)
+	}
+	unlockAgain()
+}
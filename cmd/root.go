@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/bold-minds/trek-go"
 	"github.com/spf13/cobra"
@@ -18,8 +22,22 @@ var (
 	env         string
 	outputFmt   string
 	quietMode   bool
-	verboseMode bool
+	verbosity   int
 	noColor     bool
+	profileName string
+	timeout     string
+
+	// activeContext is the named context selected by --profile, TREK_PROFILE
+	// or current-context, or nil when the flat config keys are in use.
+	activeContext *configContext
+
+	// apiTokenSource records where apiToken was read from so getClient can
+	// report which identity is in use.
+	apiTokenSource string
+
+	// configSources records where every other setting was read from, keyed
+	// by config file key, for 'trek config view'.
+	configSources = map[string]string{}
 )
 
 var rootCmd = &cobra.Command{
@@ -30,37 +48,80 @@ Create debug sessions to increase logging verbosity for specific
 users, requests, tenants, or routes without changing global log levels.`,
 }
 
-func Execute() error {
-	return rootCmd.Execute()
+// Execute runs the CLI and returns the process exit code. Errors are
+// printed here rather than by cobra so they can be written as JSON.
+func Execute() int {
+	// Ctrl+C and SIGTERM cancel the command's context, so in-flight
+	// requests stop instead of running out their timeout.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	if err == nil {
+		return exitOK
+	}
+	cliErr := classifyError(err)
+	if cliErr.Code == codeError && !cmd.SilenceUsage {
+		// The command never started, so its arguments were wrong.
+		cliErr.Code, cliErr.ExitCode = codeUsage, exitUsage
+	}
+	printError(os.Stderr, cliErr)
+	return cliErr.ExitCode
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
+	rootCmd.PersistentPreRunE = preRun
+	rootCmd.SilenceErrors = true
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &cliError{Code: codeUsage, ExitCode: exitUsage, Err: err}
+	})
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ~/.trek/config.yaml)")
 	rootCmd.PersistentFlags().StringVar(&apiEndpoint, "endpoint", "", "Trek API endpoint")
 	rootCmd.PersistentFlags().StringVar(&apiToken, "token", "", "API token")
 	rootCmd.PersistentFlags().StringVar(&orgID, "org", "", "Organization ID")
 	rootCmd.PersistentFlags().StringVar(&env, "env", "", "Environment (dev/stage/prod)")
-	rootCmd.PersistentFlags().StringVarP(&outputFmt, "output", "o", "table", "Output format: table, json, yaml")
+	rootCmd.PersistentFlags().StringVar(&timeout, "timeout", "", "Timeout for each API request, e.g. 10s or 2m; 0 disables it (default 30s)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Named context from the config file (default is current-context)")
+	rootCmd.PersistentFlags().StringVarP(&outputFmt, "output", "o", "table", "Output format: table, wide, json, yaml, ndjson, jsonpath=TEMPLATE, go-template=TEMPLATE")
+	rootCmd.PersistentFlags().StringVar(&templateFile, "template-file", "", "File holding the jsonpath or go-template for --output")
 	rootCmd.PersistentFlags().BoolVarP(&quietMode, "quiet", "q", false, "Only output IDs")
-	rootCmd.PersistentFlags().BoolVarP(&verboseMode, "verbose", "v", false, "Verbose output")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Log API calls to stderr; -vv adds headers and bodies (credentials masked)")
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output")
 }
 
 func initConfig() {
-	if apiEndpoint == "" {
-		apiEndpoint = os.Getenv("TREK_API_ENDPOINT")
+	if apiToken != "" {
+		apiTokenSource = "--token flag"
+	}
+	for key, value := range map[string]string{
+		"endpoint": apiEndpoint,
+		"org":      orgID,
+		"env":      env,
+		"profile":  profileName,
+		"timeout":  timeout,
+	} {
+		if value != "" {
+			configSources[key] = "--" + key + " flag"
+		}
 	}
+
+	setFromEnv(&apiEndpoint, "endpoint", "TREK_API_ENDPOINT")
 	if apiToken == "" {
 		apiToken = os.Getenv("TREK_API_TOKEN")
+		if apiToken != "" {
+			apiTokenSource = "TREK_API_TOKEN"
+		}
 	}
-	if orgID == "" {
-		orgID = os.Getenv("TREK_ORG_ID")
-	}
-	if env == "" {
-		env = os.Getenv("TREK_ENV")
-	}
+	setFromEnv(&orgID, "org", "TREK_ORG_ID")
+	setFromEnv(&env, "env", "TREK_ENV")
+	setFromEnv(&credentialStoreKind, "credential_store", "TREK_CREDENTIAL_STORE")
+	setFromEnv(&credentialHelper, "credential_helper", "TREK_CREDENTIAL_HELPER")
+	setFromEnv(&profileName, "profile", "TREK_PROFILE")
+	setFromEnv(&timeout, "timeout", "TREK_TIMEOUT")
+
+	loadProjectConfig()
 
 	if cfgFile == "" {
 		home, err := os.UserHomeDir()
@@ -71,58 +132,184 @@ func initConfig() {
 
 	if cfgFile != "" {
 		loadConfigFile(cfgFile)
+	} else {
+		applyProjectConfig()
 	}
 }
 
+// preRun checks the output format before any command runs. Past this point
+// failures are not usage mistakes, so cobra stops printing the usage text.
+func preRun(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(cmd, args); err != nil {
+		return &cliError{Code: codeUsage, ExitCode: exitUsage, Err: err}
+	}
+	if timeout != "" {
+		d, err := parseTimeout(timeout)
+		if err != nil {
+			return usageError("invalid timeout %q from %s: %w", timeout, configSources["timeout"], err)
+		}
+		requestTimeout = d
+	}
+	cmd.SilenceUsage = true
+	return nil
+}
+
+// setFromEnv fills an unset value from an environment variable.
+func setFromEnv(value *string, key, envVar string) {
+	if *value != "" {
+		return
+	}
+	if v := os.Getenv(envVar); v != "" {
+		*value = v
+		configSources[key] = envVar
+	}
+}
+
+// setFromConfig fills an unset value from the config file.
+func setFromConfig(value *string, key, configValue, source string) {
+	if *value != "" || configValue == "" {
+		return
+	}
+	*value = configValue
+	configSources[key] = source
+}
+
+// loadConfigFile fills unset values from the active context, then the
+// project file, then the flat keys of the config file at path.
 func loadConfigFile(path string) {
+	cfg := parseConfigFile(path)
+
+	// The active context overrides the flat keys, which remain the
+	// fallback for files written before contexts existed.
+	if profileName == "" && cfg.CurrentContext != "" {
+		profileName = cfg.CurrentContext
+		configSources["profile"] = "current-context in " + path
+	}
+	if profileName != "" {
+		activeContext = cfg.context(profileName)
+	}
+	if c := activeContext; c != nil {
+		source := "context " + c.Name
+		setFromConfig(&apiEndpoint, "endpoint", c.Endpoint, source)
+		if apiToken == "" && c.Token != "" && c.Auth != contextAuthUser {
+			apiToken = c.Token
+			apiTokenSource = source
+		}
+		setFromConfig(&orgID, "org", c.Org, source)
+		setFromConfig(&env, "env", c.Env, source)
+	}
+
+	// A project pins its org and env over the flat keys, but an explicitly
+	// selected context still wins.
+	applyProjectConfig()
+
+	source := "config file " + path
+	setFromConfig(&apiEndpoint, "endpoint", cfg.Endpoint, source)
+	if apiToken == "" && cfg.Token != "" && !activeContext.usesLogin() {
+		apiToken = cfg.Token
+		apiTokenSource = source
+	}
+	setFromConfig(&orgID, "org", cfg.Org, source)
+	setFromConfig(&env, "env", cfg.Env, source)
+	setFromConfig(&credentialStoreKind, "credential_store", cfg.CredentialStore, source)
+	setFromConfig(&credentialHelper, "credential_helper", cfg.CredentialHelper, source)
+	setFromConfig(&timeout, "timeout", cfg.Timeout, source)
+}
+
+// parseConfigFile reads the config file at path. A missing file is empty;
+// an unreadable one is reported and treated as empty.
+func parseConfigFile(path string) *configFile {
+	var cfg configFile
 	data, err := os.ReadFile(path)
 	if err != nil {
 		// File not found is expected, other errors should be logged
 		if !os.IsNotExist(err) {
 			fmt.Fprintf(os.Stderr, "Warning: failed to read config file %s: %v\n", path, err)
 		}
-		return
-	}
-
-	var cfg struct {
-		Endpoint string `yaml:"endpoint"`
-		Token    string `yaml:"token"`
-		Org      string `yaml:"org"`
-		Env      string `yaml:"env"`
+		return &cfg
 	}
-
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to parse config file %s: %v\n", path, err)
-		return
+		return &configFile{}
+	}
+	return &cfg
+}
+
+// authIdentity describes who API calls are made as.
+type authIdentity struct {
+	Kind   string // "service token" or "human user"
+	Source string
+	Email  string
+}
+
+func (id authIdentity) String() string {
+	if id.Email != "" {
+		return fmt.Sprintf("%s %s (%s)", id.Kind, id.Email, id.Source)
 	}
+	return fmt.Sprintf("%s (%s)", id.Kind, id.Source)
+}
 
-	if apiEndpoint == "" && cfg.Endpoint != "" {
-		apiEndpoint = cfg.Endpoint
+// resolveToken picks the bearer token for API calls. Precedence:
+//
+//  1. --token flag
+//  2. TREK_API_TOKEN
+//  3. token in the active context, then in the config file
+//  4. credentials stored by 'trek auth login' for the active context
+//
+// The first three are service tokens; the last is a human user. A context
+// with auth: user skips the config tokens, and one with auth: token never
+// falls back to login credentials.
+func resolveToken() (string, authIdentity, error) {
+	if profileName != "" && activeContext == nil {
+		return "", authIdentity{}, usageError("context %q not found in config file (see 'trek config get-contexts')", profileName)
 	}
-	if apiToken == "" && cfg.Token != "" {
-		apiToken = cfg.Token
+
+	if apiToken != "" {
+		source := apiTokenSource
+		if source == "" {
+			source = "--token flag"
+		}
+		return apiToken, authIdentity{Kind: "service token", Source: source}, nil
 	}
-	if orgID == "" && cfg.Org != "" {
-		orgID = cfg.Org
+
+	if activeContext.usesToken() {
+		return "", authIdentity{}, authError("API token required: context %q uses auth: token (--token, TREK_API_TOKEN or token in the context)", activeContext.Name)
 	}
-	if env == "" && cfg.Env != "" {
-		env = cfg.Env
+
+	ctx, cancel := withTimeout(rootContext())
+	defer cancel()
+
+	creds, err := ensureFreshCredentials(ctx)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", authIdentity{}, authError("API token required (--token or TREK_API_TOKEN, or run 'trek auth login')")
+		}
+		return "", authIdentity{}, authError("API token required: %w", err)
 	}
+
+	return creds.AccessToken, authIdentity{Kind: "human user", Source: "trek auth login", Email: creds.Email}, nil
 }
 
 func getClient() (*trek.Client, error) {
 	if apiEndpoint == "" {
-		return nil, fmt.Errorf("API endpoint required (--endpoint or TREK_API_ENDPOINT)")
+		return nil, usageError("API endpoint required (--endpoint or TREK_API_ENDPOINT)")
 	}
-	if apiToken == "" {
-		return nil, fmt.Errorf("API token required (--token or TREK_API_TOKEN)")
+	token, identity, err := resolveToken()
+	if err != nil {
+		return nil, err
 	}
 	if orgID == "" {
-		return nil, fmt.Errorf("org ID required (--org or TREK_ORG_ID)")
+		return nil, usageError("org ID required (--org or TREK_ORG_ID)")
 	}
 	if env == "" {
-		return nil, fmt.Errorf("env required (--env or TREK_ENV)")
+		return nil, usageError("env required (--env or TREK_ENV)")
+	}
+
+	var opts []trek.Option
+	if verbosity > 0 {
+		fmt.Fprintf(os.Stderr, "Authenticating as %s\n", identity)
+		opts = append(opts, traceOption(verbosity > 1))
 	}
 
-	return trek.NewClient(apiEndpoint, apiToken, orgID, env), nil
+	return trek.NewClient(apiEndpoint, token, orgID, env, opts...), nil
 }
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoadConfigFile(t *testing.T) {
@@ -143,6 +144,9 @@ func TestLoadConfigFileInvalidYAML(t *testing.T) {
 }
 
 func TestGetClientValidation(t *testing.T) {
+	// Keep stored credentials from a real login out of the test.
+	t.Setenv("HOME", t.TempDir())
+
 	tests := []struct {
 		name        string
 		endpoint    string
@@ -207,6 +211,98 @@ func TestGetClientValidation(t *testing.T) {
 	}
 }
 
+func TestResolveTokenPrecedence(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	restoreToken(t)
+
+	if err := saveCredentials(StoredCredentials{
+		AccessToken: "user-token",
+		ExpiresAt:   time.Now().Add(time.Hour),
+		Email:       "dev@example.com",
+	}); err != nil {
+		t.Fatalf("failed to save credentials: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		source     string
+		wantToken  string
+		wantKind   string
+		wantSource string
+	}{
+		{
+			name:       "service token wins over stored credentials",
+			token:      "svc-token",
+			source:     "TREK_API_TOKEN",
+			wantToken:  "svc-token",
+			wantKind:   "service token",
+			wantSource: "TREK_API_TOKEN",
+		},
+		{
+			name:       "falls back to stored credentials",
+			wantToken:  "user-token",
+			wantKind:   "human user",
+			wantSource: "trek auth login",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiToken = tt.token
+			apiTokenSource = tt.source
+
+			token, identity, err := resolveToken()
+			if err != nil {
+				t.Fatalf("resolveToken() unexpected error: %v", err)
+			}
+			if token != tt.wantToken {
+				t.Errorf("token = %q, want %q", token, tt.wantToken)
+			}
+			if identity.Kind != tt.wantKind {
+				t.Errorf("identity.Kind = %q, want %q", identity.Kind, tt.wantKind)
+			}
+			if identity.Source != tt.wantSource {
+				t.Errorf("identity.Source = %q, want %q", identity.Source, tt.wantSource)
+			}
+		})
+	}
+
+}
+
+func TestResolveTokenExpiredCredentials(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	restoreToken(t)
+	apiToken = ""
+	apiTokenSource = ""
+
+	if err := saveCredentials(StoredCredentials{
+		AccessToken: "stale-token",
+		ExpiresAt:   time.Now().Add(-time.Hour),
+	}); err != nil {
+		t.Fatalf("failed to save credentials: %v", err)
+	}
+
+	_, _, err := resolveToken()
+	if err == nil {
+		t.Fatal("expected error for expired credentials")
+	}
+	if !contains(err.Error(), "trek auth login") {
+		t.Errorf("error = %q, want hint to run trek auth login", err.Error())
+	}
+}
+
+// restoreToken puts the token globals back after the test so later tests
+// see the same state they would have without it.
+func restoreToken(t *testing.T) {
+	t.Helper()
+	token, source := apiToken, apiTokenSource
+	t.Cleanup(func() {
+		apiToken = token
+		apiTokenSource = source
+	})
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
 }
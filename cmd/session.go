@@ -1,6 +1,12 @@
 package cmd
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bold-minds/trek-go"
 	"github.com/spf13/cobra"
 )
 
@@ -13,3 +19,176 @@ var sessionCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(sessionCmd)
 }
+
+// sessionView is the output form of a session.
+type sessionView struct {
+	ID        string            `json:"id" yaml:"id"`
+	Status    string            `json:"status" yaml:"status"`
+	Level     string            `json:"level" yaml:"level"`
+	Selector  selectorView      `json:"selector" yaml:"selector"`
+	ExpiresAt time.Time         `json:"expires_at" yaml:"expires_at"`
+	Labels    map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Caps      capsView          `json:"caps" yaml:"caps"`
+	Reason    string            `json:"reason,omitempty" yaml:"reason,omitempty"`
+	CreatedBy string            `json:"created_by,omitempty" yaml:"created_by,omitempty"`
+	CreatedAt time.Time         `json:"created_at" yaml:"created_at"`
+}
+
+func (v sessionView) id() string { return v.ID }
+
+// selectorFieldKeys maps the --selector keys that name a built-in selector
+// field, short or as in its JSON form, to that field. Other keys are custom.
+var selectorFieldKeys = map[string]func(*trek.Selector) *string{
+	"user":       func(s *trek.Selector) *string { return &s.UserID },
+	"user_id":    func(s *trek.Selector) *string { return &s.UserID },
+	"request":    func(s *trek.Selector) *string { return &s.RequestID },
+	"request_id": func(s *trek.Selector) *string { return &s.RequestID },
+	"tenant":     func(s *trek.Selector) *string { return &s.TenantID },
+	"tenant_id":  func(s *trek.Selector) *string { return &s.TenantID },
+	"route":      func(s *trek.Selector) *string { return &s.Route },
+}
+
+// parseSelectorFlags applies --selector key=value flags to sel. Built-in
+// keys set their field and other keys go to Selector.Custom, so create and
+// revoke read --selector user=u123 the same way.
+func parseSelectorFlags(flags []string, sel *trek.Selector) error {
+	for _, s := range flags {
+		key, value, ok := strings.Cut(s, "=")
+		if !ok || key == "" || value == "" {
+			return usageError("invalid selector format %q: expected key=value", s)
+		}
+		if field, builtin := selectorFieldKeys[key]; builtin {
+			if *field(sel) != "" {
+				return usageError("selector key %q given more than once", key)
+			}
+			*field(sel) = value
+			continue
+		}
+		if _, dup := sel.Custom[key]; dup {
+			return usageError("selector key %q given more than once", key)
+		}
+		if sel.Custom == nil {
+			sel.Custom = make(map[string]string)
+		}
+		sel.Custom[key] = value
+	}
+	return nil
+}
+
+// selectorKeys lists the keys set in sel, named as in its JSON form.
+func selectorKeys(sel trek.Selector) []string {
+	var keys []string
+	for key, value := range map[string]string{
+		"user_id":    sel.UserID,
+		"request_id": sel.RequestID,
+		"tenant_id":  sel.TenantID,
+		"route":      sel.Route,
+	} {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	for key := range sel.Custom {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+type selectorView struct {
+	UserID    string            `json:"user_id,omitempty" yaml:"user_id,omitempty"`
+	RequestID string            `json:"request_id,omitempty" yaml:"request_id,omitempty"`
+	TenantID  string            `json:"tenant_id,omitempty" yaml:"tenant_id,omitempty"`
+	Route     string            `json:"route,omitempty" yaml:"route,omitempty"`
+	Custom    map[string]string `json:"custom,omitempty" yaml:"custom,omitempty"`
+}
+
+type capsView struct {
+	MaxDebugEventsPerRequest int `json:"max_debug_events_per_request,omitempty" yaml:"max_debug_events_per_request,omitempty"`
+	MaxDebugEventsPerSession int `json:"max_debug_events_per_session,omitempty" yaml:"max_debug_events_per_session,omitempty"`
+}
+
+func newSessionView(s trek.Session) sessionView {
+	return sessionView{
+		ID:        s.ID,
+		Status:    sessionStatus(s),
+		Level:     string(s.Level),
+		Selector:  selectorView(s.Selector),
+		ExpiresAt: s.ExpiresAt,
+		Labels:    s.Labels,
+		Caps:      capsView(s.Caps),
+		Reason:    s.Reason,
+		CreatedBy: s.CreatedBy,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+func newSessionViews(sessions []trek.Session) []sessionView {
+	views := make([]sessionView, len(sessions))
+	for i, s := range sessions {
+		views[i] = newSessionView(s)
+	}
+	return views
+}
+
+// sessionStatus is the status the API reports, or one derived from the
+// expiry for servers that do not report it.
+func sessionStatus(s trek.Session) string {
+	if s.Status != "" {
+		return s.Status
+	}
+	if time.Now().After(s.ExpiresAt) {
+		return "expired"
+	}
+	return "active"
+}
+
+// sessionColumns are the columns of 'trek session list'; Name is what
+// --columns takes.
+var sessionColumns = []column[sessionView]{
+	{Name: "id", Header: "ID", Value: func(s sessionView) string { return s.ID }, Truncate: 28},
+	{Name: "status", Header: "STATUS", Value: func(s sessionView) string { return s.Status },
+		Color: func(s sessionView) string { return sessionStatusColor(s.Status, s.ExpiresAt) }},
+	{Name: "level", Header: "LEVEL", Value: func(s sessionView) string { return s.Level },
+		Color: func(s sessionView) string { return levelColor(s.Level) }},
+	{Name: "expires", Header: "EXPIRES", Value: func(s sessionView) string { return s.ExpiresAt.Format("2006-01-02 15:04:05") }},
+	{Name: "selector", Header: "SELECTOR", Value: func(s sessionView) string { return formatSelector(trek.Selector(s.Selector)) }, Truncate: 30},
+	{Name: "labels", Header: "LABELS", Value: func(s sessionView) string { return formatLabels(s.Labels) }, Wide: true, Truncate: 30},
+	{Name: "reason", Header: "REASON", Value: func(s sessionView) string { return s.Reason }, Wide: true, Truncate: 30},
+	{Name: "created-by", Header: "CREATED BY", Value: func(s sessionView) string { return s.CreatedBy }, Wide: true, Truncate: 24},
+	{Name: "created", Header: "CREATED", Value: func(s sessionView) string { return formatTime(s.CreatedAt) }, Wide: true},
+	{Name: "max-request", Header: "MAX/REQUEST", Value: func(s sessionView) string { return formatCap(s.Caps.MaxDebugEventsPerRequest) }, Wide: true},
+	{Name: "max-session", Header: "MAX/SESSION", Value: func(s sessionView) string { return formatCap(s.Caps.MaxDebugEventsPerSession) }, Wide: true},
+}
+
+// sessionSortKeys are the values --sort-by takes.
+var sessionSortKeys = map[string]func(a, b sessionView) int{
+	"expires": func(a, b sessionView) int { return a.ExpiresAt.Compare(b.ExpiresAt) },
+	"created": func(a, b sessionView) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"level":   func(a, b sessionView) int { return strings.Compare(a.Level, b.Level) },
+}
+
+// sortSessions orders sessions by key, breaking ties by ID.
+func sortSessions(views []sessionView, key string) {
+	cmp := sessionSortKeys[key]
+	slices.SortStableFunc(views, func(a, b sessionView) int {
+		if c := cmp(a, b); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func formatCap(n int) string {
+	if n <= 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
+}
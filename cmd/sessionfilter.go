@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bold-minds/trek-go"
+)
+
+// sessionFilter selects sessions for 'trek session list' and bulk revoke.
+// Every set field must match.
+type sessionFilter struct {
+	status    string
+	labels    []labelRequirement
+	selector  trek.Selector
+	level     string
+	createdBy []string
+	before    time.Time
+}
+
+// labelRequirement is one term of a label selector such as team=payments,
+// env!=dev, ticket or !ticket.
+type labelRequirement struct {
+	key   string
+	op    string // "=", "!=", "exists" or "!exists"
+	value string
+}
+
+func (r labelRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.key]
+	switch r.op {
+	case "=":
+		return ok && value == r.value
+	case "!=":
+		return value != r.value
+	case "exists":
+		return ok
+	default:
+		return !ok
+	}
+}
+
+// parseLabelSelector parses a comma-separated label selector, e.g.
+// "team=payments,env!=dev".
+func parseLabelSelector(selector string) ([]labelRequirement, error) {
+	var reqs []labelRequirement
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		var r labelRequirement
+		switch {
+		case term == "":
+			continue
+		case strings.HasPrefix(term, "!"):
+			r = labelRequirement{key: term[1:], op: "!exists"}
+		case strings.Contains(term, "!="):
+			key, value, _ := strings.Cut(term, "!=")
+			r = labelRequirement{key: key, op: "!=", value: value}
+		case strings.Contains(term, "="):
+			key, value, _ := strings.Cut(term, "=")
+			r = labelRequirement{key: key, op: "=", value: strings.TrimPrefix(value, "=")}
+		default:
+			r = labelRequirement{key: term, op: "exists"}
+		}
+		r.key = strings.TrimSpace(r.key)
+		if r.key == "" || strings.ContainsAny(r.key, "!=") {
+			return nil, usageError("invalid label selector %q: expected key=value, key!=value, key or !key", term)
+		}
+		reqs = append(reqs, r)
+	}
+	return reqs, nil
+}
+
+// labelEquals turns key=value labels into label requirements.
+func labelEquals(labels map[string]string) []labelRequirement {
+	reqs := make([]labelRequirement, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		reqs = append(reqs, labelRequirement{key: key, op: "=", value: labels[key]})
+	}
+	return reqs
+}
+
+// loginIdentities returns the names a session created by the logged-in user
+// may carry in CreatedBy.
+func loginIdentities() ([]string, error) {
+	creds, err := loadCredentials()
+	if err != nil {
+		return nil, authError("filtering by your own sessions needs a login from 'trek auth login': %v", err)
+	}
+	var ids []string
+	for _, id := range []string{creds.Email, creds.Subject} {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, authError("the stored login has no email or subject to match sessions by")
+	}
+	return ids, nil
+}
+
+func (f sessionFilter) matches(s trek.Session) bool {
+	if f.status != "" && sessionStatus(s) != f.status {
+		return false
+	}
+	for _, r := range f.labels {
+		if !r.matches(s.Labels) {
+			return false
+		}
+	}
+	for _, c := range []struct{ want, got string }{
+		{f.selector.UserID, s.Selector.UserID},
+		{f.selector.RequestID, s.Selector.RequestID},
+		{f.selector.TenantID, s.Selector.TenantID},
+		{f.selector.Route, s.Selector.Route},
+		{f.level, string(s.Level)},
+	} {
+		if c.want != "" && c.want != c.got {
+			return false
+		}
+	}
+	for k, v := range f.selector.Custom {
+		if s.Selector.Custom[k] != v {
+			return false
+		}
+	}
+	if len(f.createdBy) > 0 && !slices.Contains(f.createdBy, s.CreatedBy) {
+		return false
+	}
+	// A session without a creation time has no known age, so it never
+	// matches an age filter.
+	if !f.before.IsZero() && (s.CreatedAt.IsZero() || !s.CreatedAt.Before(f.before)) {
+		return false
+	}
+	return true
+}
+
+// filter returns the sessions f matches.
+func (f sessionFilter) filter(sessions []trek.Session) []trek.Session {
+	var out []trek.Session
+	for _, s := range sessions {
+		if f.matches(s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// validateLevel checks a --level filter.
+func validateLevel(lvl string) error {
+	if lvl != "" && lvl != string(trek.LevelDebug) && lvl != string(trek.LevelTrace) {
+		return usageError("invalid --level %q: use debug or trace", lvl)
+	}
+	return nil
+}
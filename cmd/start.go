@@ -1,8 +1,10 @@
 package cmd
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"strings"
 	"time"
 
@@ -11,14 +13,23 @@ import (
 )
 
 var (
-	userID    string
-	requestID string
-	tenantID  string
-	route     string
-	ttl       time.Duration
-	level     string
-	reason    string
-	labels    []string
+	userID        string
+	requestID     string
+	tenantID      string
+	route         string
+	ttl           time.Duration
+	level         string
+	reason        string
+	labels        []string
+	selectors     []string
+	createDryRun  bool
+	maxPerRequest int
+	maxPerSession int
+	createWait    bool
+	waitTimeout   time.Duration
+	waitService   string
+
+	sessionTemplateName string
 )
 
 var sessionCreateCmd = &cobra.Command{
@@ -29,7 +40,24 @@ var sessionCreateCmd = &cobra.Command{
 Examples:
   trek session create --user u123 --ttl 15m --level debug --reason "investigating order issue"
   trek session create --route "/api/orders*" --ttl 10m --level trace
-  trek session create --tenant t456 --ttl 30m --level debug`,
+  trek session create --tenant t456 --ttl 30m --level debug
+  trek session create --template checkout --user u123
+  trek session create --selector region=eu-west-1 --selector plan=pro --ttl 10m
+  trek session create --user u123 --ttl 10m --wait
+  trek session create --user u123 --max-events-per-request 50
+
+Defaults for labels, --ttl and --level, and named templates, come from a
+.trek.yaml in the current directory or a parent. Flags override them.
+
+--selector matches custom request attributes.
+
+The request is checked against the org's policy before it is sent, and every
+violation is reported at once. --dry-run stops after the check and prints the
+request that would be sent.
+
+--wait blocks until the session appears in the active-session snapshot SDKs
+poll for --service, so test traffic sent afterwards is debugged. It fails
+with exit code 6 if that takes longer than --wait-timeout.`,
 	RunE: runCreate,
 }
 
@@ -40,10 +68,18 @@ func init() {
 	sessionCreateCmd.Flags().StringVar(&requestID, "request", "", "Target request ID")
 	sessionCreateCmd.Flags().StringVar(&tenantID, "tenant", "", "Target tenant ID")
 	sessionCreateCmd.Flags().StringVar(&route, "route", "", "Target route (supports * prefix matching)")
+	sessionCreateCmd.Flags().StringArrayVar(&selectors, "selector", nil, "Selector in key=value format; user, request, tenant and route set the built-in fields, other keys are custom (can be repeated)")
 	sessionCreateCmd.Flags().DurationVar(&ttl, "ttl", 15*time.Minute, "Session TTL (e.g., 15m, 1h)")
 	sessionCreateCmd.Flags().StringVar(&level, "level", "debug", "Log level (debug or trace)")
 	sessionCreateCmd.Flags().StringVar(&reason, "reason", "", "Reason for enabling debug (required by policy)")
 	sessionCreateCmd.Flags().StringArrayVar(&labels, "label", nil, "Labels in key=value format (can be repeated)")
+	sessionCreateCmd.Flags().StringVar(&sessionTemplateName, "template", "", "Session template from .trek.yaml")
+	sessionCreateCmd.Flags().IntVar(&maxPerRequest, "max-events-per-request", 0, "Cap on debug events per request (default: policy default)")
+	sessionCreateCmd.Flags().IntVar(&maxPerSession, "max-events-per-session", 0, "Cap on debug events per session (default: policy default)")
+	sessionCreateCmd.Flags().BoolVar(&createDryRun, "dry-run", false, "Check the request against policy and print it without creating a session")
+	sessionCreateCmd.Flags().BoolVar(&createWait, "wait", false, "Wait until the session is live for SDKs")
+	sessionCreateCmd.Flags().DurationVar(&waitTimeout, "wait-timeout", time.Minute, "How long --wait waits")
+	sessionCreateCmd.Flags().StringVar(&waitService, "service", "", "Service whose SDK snapshot --wait checks (default: the project's service, or cli)")
 }
 
 func runCreate(cmd *cobra.Command, args []string) error {
@@ -52,19 +88,22 @@ func runCreate(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	labelMap, err := applySessionDefaults(cmd)
+	if err != nil {
+		return err
+	}
+
+	if createWait && waitTimeout <= 0 {
+		return usageError("--wait-timeout must be positive, got %s", waitTimeout)
+	}
+
 	selector := trek.Selector{
 		UserID:    userID,
 		RequestID: requestID,
 		TenantID:  tenantID,
 		Route:     route,
 	}
-
-	if trek.IsEmptySelector(selector) {
-		return fmt.Errorf("at least one selector field required (--user, --request, --tenant, or --route)")
-	}
-
-	labelMap, err := parseLabels(labels)
-	if err != nil {
+	if err := parseSelectorFlags(selectors, &selector); err != nil {
 		return err
 	}
 
@@ -74,23 +113,161 @@ func runCreate(cmd *cobra.Command, args []string) error {
 		TTLSeconds: int(ttl.Seconds()),
 		Reason:     reason,
 		Labels:     labelMap,
+		Caps:       capsFromFlags(maxPerRequest, maxPerSession),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := withTimeout(cmd.Context())
 	defer cancel()
 
+	err = preflight(ctx, client, createDryRun, checkCreateUsage(req), func(policy trek.Policy) []policyViolation {
+		return checkCreateRequest(req, policy)
+	})
+	if err != nil {
+		return err
+	}
+
+	if createDryRun {
+		return printItem(newCreateRequestView(req), func(w io.Writer, v createRequestView) {
+			fmt.Fprintln(w, "Dry run: the request passes policy checks. It would send:")
+			data, _ := json.MarshalIndent(v, "  ", "  ")
+			fmt.Fprintf(w, "  %s\n", data)
+		})
+	}
+
 	resp, err := client.CreateSession(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
 
-	fmt.Printf("Session created successfully\n")
-	fmt.Printf("  ID:         %s\n", resp.ID)
-	fmt.Printf("  Status:     %s\n", resp.Status)
-	fmt.Printf("  Expires:    %s\n", resp.ExpiresAt.Format(time.RFC3339))
-	fmt.Printf("  Propagation: ≤10s (poll interval 5s)\n")
+	created := createdSessionView{
+		ID:        resp.ID,
+		Status:    resp.Status,
+		ExpiresAt: resp.ExpiresAt,
+	}
+	if createWait {
+		took, err := waitForPropagation(cmd.Context(), client, resp.ID, propagationService(labelMap), waitTimeout)
+		if err != nil {
+			return err
+		}
+		created.Live = true
+		created.PropagationSeconds = took.Seconds()
+	}
 
-	return nil
+	return printItem(created, func(w io.Writer, v createdSessionView) {
+		fmt.Fprintf(w, "Session created successfully\n")
+		fmt.Fprintf(w, "  ID:         %s\n", v.ID)
+		fmt.Fprintf(w, "  Status:     %s\n", paint(sessionStatusColor(v.Status, v.ExpiresAt), v.Status))
+		fmt.Fprintf(w, "  Expires:    %s\n", v.ExpiresAt.Format(time.RFC3339))
+		if v.Live {
+			fmt.Fprintf(w, "  Propagation: live after %s\n", (time.Duration(v.PropagationSeconds * float64(time.Second))).Round(time.Millisecond))
+		} else {
+			fmt.Fprintf(w, "  Propagation: ≤10s (poll interval 5s)\n")
+		}
+	})
+}
+
+type createdSessionView struct {
+	ID        string    `json:"id" yaml:"id"`
+	Status    string    `json:"status" yaml:"status"`
+	ExpiresAt time.Time `json:"expires_at" yaml:"expires_at"`
+	// Live and PropagationSeconds are set by --wait.
+	Live               bool    `json:"live,omitempty" yaml:"live,omitempty"`
+	PropagationSeconds float64 `json:"propagation_seconds,omitempty" yaml:"propagation_seconds,omitempty"`
+}
+
+func (v createdSessionView) id() string { return v.ID }
+
+// createRequestView is the output form of the request --dry-run would send.
+// Its JSON form matches the API's.
+type createRequestView struct {
+	Selector   selectorView      `json:"selector" yaml:"selector"`
+	Level      string            `json:"level" yaml:"level"`
+	TTLSeconds int               `json:"ttl_seconds" yaml:"ttl_seconds"`
+	Reason     string            `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Caps       *capsView         `json:"caps,omitempty" yaml:"caps,omitempty"`
+}
+
+func newCreateRequestView(req trek.CreateSessionRequest) createRequestView {
+	v := createRequestView{
+		Selector:   selectorView(req.Selector),
+		Level:      string(req.Level),
+		TTLSeconds: req.TTLSeconds,
+		Reason:     req.Reason,
+		Labels:     req.Labels,
+	}
+	if req.Caps != nil {
+		caps := capsView(*req.Caps)
+		v.Caps = &caps
+	}
+	return v
+}
+
+// applySessionDefaults fills the selector, --ttl, --level and --reason from
+// --template and then the project file where the user gave no flag. Labels
+// are merged: the project's service and labels, then the template's, then
+// --label.
+func applySessionDefaults(cmd *cobra.Command) (map[string]string, error) {
+	var tmpl *sessionTemplate
+	if sessionTemplateName != "" {
+		t, err := project.template(sessionTemplateName)
+		if err != nil {
+			return nil, err
+		}
+		tmpl = t
+	}
+
+	merged := make(map[string]string)
+	ttlDefault, levelDefault := "", ""
+	if project != nil {
+		if project.Service != "" {
+			merged["service"] = project.Service
+		}
+		maps.Copy(merged, project.Labels)
+		ttlDefault, levelDefault = project.TTL, project.Level
+	}
+	if tmpl != nil {
+		for _, f := range []struct {
+			value    *string
+			fallback string
+		}{
+			{&userID, tmpl.User},
+			{&requestID, tmpl.Request},
+			{&tenantID, tmpl.Tenant},
+			{&route, tmpl.Route},
+			{&reason, tmpl.Reason},
+		} {
+			if *f.value == "" {
+				*f.value = f.fallback
+			}
+		}
+		maps.Copy(merged, tmpl.Labels)
+		if tmpl.TTL != "" {
+			ttlDefault = tmpl.TTL
+		}
+		if tmpl.Level != "" {
+			levelDefault = tmpl.Level
+		}
+	}
+
+	if !cmd.Flags().Changed("ttl") && ttlDefault != "" {
+		// Validated when the project file was read.
+		ttl, _ = parseProjectTTL(ttlDefault)
+	}
+	if !cmd.Flags().Changed("level") && levelDefault != "" {
+		level = levelDefault
+	}
+
+	flagLabels, err := parseLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(merged, flagLabels)
+
+	if len(merged) == 0 {
+		return nil, nil
+	}
+	return merged, nil
 }
 
 func parseLabels(labels []string) (map[string]string, error) {
@@ -101,9 +278,31 @@ func parseLabels(labels []string) (map[string]string, error) {
 	for _, l := range labels {
 		parts := strings.SplitN(l, "=", 2)
 		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid label format %q: expected key=value", l)
+			return nil, usageError("invalid label format %q: expected key=value", l)
 		}
 		result[parts[0]] = parts[1]
 	}
 	return result, nil
 }
+
+// propagationService returns the service --wait checks: --service, else the
+// session's service label from the project file, template or --label, else
+// cli.
+func propagationService(labels map[string]string) string {
+	if waitService != "" {
+		return waitService
+	}
+	if svc := labels["service"]; svc != "" {
+		return svc
+	}
+	return "cli"
+}
+
+// capsFromFlags returns the caps given by --max-events-per-request and
+// --max-events-per-session, or nil if neither was set.
+func capsFromFlags(perRequest, perSession int) *trek.Caps {
+	if perRequest == 0 && perSession == 0 {
+		return nil
+	}
+	return &trek.Caps{MaxDebugEventsPerRequest: perRequest, MaxDebugEventsPerSession: perSession}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"maps"
 	"testing"
 	"time"
 
@@ -31,6 +32,7 @@ func TestCreateCommandFlags(t *testing.T) {
 		{"request flag", "request", "string"},
 		{"tenant flag", "tenant", "string"},
 		{"route flag", "route", "string"},
+		{"selector flag", "selector", "stringArray"},
 		{"ttl flag", "ttl", "duration"},
 		{"level flag", "level", "string"},
 		{"reason flag", "reason", "string"},
@@ -67,11 +69,9 @@ func TestCreateCommandDefaults(t *testing.T) {
 }
 
 func TestRunCreateValidation_EmptySelector(t *testing.T) {
-	// Reset global vars
-	userID = ""
-	requestID = ""
-	tenantID = ""
-	route = ""
+	startDevServer(t, trek.Policy{})
+	captureOutput(t, outputTable)
+	setCreateFlags(t, "", 15*time.Minute, "")
 
 	err := runCreate(sessionCreateCmd, []string{})
 
@@ -246,3 +246,67 @@ func TestCreateSessionRequest(t *testing.T) {
 		t.Errorf("Reason = %q, want %q", req.Reason, "testing")
 	}
 }
+
+func TestParseSelectorFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    trek.Selector
+		input   []string
+		want    trek.Selector
+		wantErr string
+	}{
+		{name: "none", input: nil, want: trek.Selector{}},
+		{name: "several", input: []string{"region=eu-west-1", "plan=pro"}, want: trek.Selector{Custom: map[string]string{"region": "eu-west-1", "plan": "pro"}}},
+		{name: "value with equals", input: []string{"query=a=b"}, want: trek.Selector{Custom: map[string]string{"query": "a=b"}}},
+		{name: "short builtin key", input: []string{"user=u1", "tenant=t1"}, want: trek.Selector{UserID: "u1", TenantID: "t1"}},
+		{name: "full builtin key", input: []string{"request_id=r1", "route=/api/*"}, want: trek.Selector{RequestID: "r1", Route: "/api/*"}},
+		{name: "missing value", input: []string{"region"}, wantErr: "expected key=value"},
+		{name: "empty value", input: []string{"region="}, wantErr: "expected key=value"},
+		{name: "duplicate", input: []string{"plan=a", "plan=b"}, wantErr: "more than once"},
+		{name: "builtin given twice", input: []string{"user=u1", "user_id=u2"}, wantErr: "more than once"},
+		{name: "builtin also set by flag", base: trek.Selector{UserID: "u1"}, input: []string{"user=u2"}, wantErr: "more than once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.base
+			err := parseSelectorFlags(tt.input, &got)
+			if tt.wantErr != "" {
+				if got := classifyError(err); got.ExitCode != exitUsage || !contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseSelectorFlags() error = %v, want usage error %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSelectorFlags() error = %v", err)
+			}
+			if got.UserID != tt.want.UserID || got.RequestID != tt.want.RequestID || got.TenantID != tt.want.TenantID ||
+				got.Route != tt.want.Route || !maps.Equal(got.Custom, tt.want.Custom) {
+				t.Errorf("parseSelectorFlags() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCreateCustomSelector(t *testing.T) {
+	srv := startDevServer(t, trek.Policy{AllowedSelectorKeys: []string{"user_id", "region"}})
+	captureOutput(t, outputJSON)
+	setCreateFlags(t, "u1", 10*time.Minute, "")
+
+	selectors = []string{"plan=pro"}
+	err := runCreate(sessionCreateCmd, nil)
+	if got := classifyError(err); got.ExitCode != exitPolicy || !contains(err.Error(), "allowed keys: user_id, region") {
+		t.Fatalf("create with a disallowed key = %v, want a policy error", err)
+	}
+	if len(srv.sessions) != 0 {
+		t.Fatalf("a session was created despite the policy error")
+	}
+
+	selectors = []string{"region=eu-west-1"}
+	if err := runCreate(sessionCreateCmd, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if got := srv.sessions[0].Selector.Custom["region"]; got != "eu-west-1" {
+		t.Errorf("custom selector region = %q, want eu-west-1", got)
+	}
+}
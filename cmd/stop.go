@@ -1,9 +1,8 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"time"
+	"io"
 
 	"github.com/spf13/cobra"
 )
@@ -15,12 +14,18 @@ var (
 
 var sessionRevokeCmd = &cobra.Command{
 	Use:   "revoke",
-	Short: "Revoke a debug session",
-	Long: `Revoke an active debug session by ID.
+	Short: "Revoke debug sessions",
+	Long: `Revoke an active debug session by ID, or every active session matching
+filters. Filters combine: a session must match all of them. Matching
+sessions are listed and confirmed before they are revoked.
 
 Example:
   trek session revoke sess_abc123
-  trek session revoke sess_abc123 --yes`,
+  trek session revoke sess_abc123 --yes
+  trek session revoke --label team=payments
+  trek session revoke --selector user=u123 --level trace
+  trek session revoke --created-by me --older-than 1h --yes
+  trek session revoke --all`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runRevoke,
 }
@@ -38,8 +43,14 @@ func runRevoke(cmd *cobra.Command, args []string) error {
 	if len(args) > 0 {
 		sessionID = args[0]
 	}
+	if bulkRevokeRequested() {
+		if sessionID != "" {
+			return usageError("give a session ID or filters, not both")
+		}
+		return runBulkRevoke(cmd)
+	}
 	if sessionID == "" {
-		return fmt.Errorf("session ID required\n  Usage: trek session revoke <session_id>\n  Example: trek session revoke sess_abc123")
+		return usageError("session ID required\n  Usage: trek session revoke <session_id>\n  Example: trek session revoke sess_abc123")
 	}
 
 	// Interactive confirmation unless --yes is provided
@@ -59,13 +70,22 @@ func runRevoke(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := withTimeout(cmd.Context())
 	defer cancel()
 
 	if err := client.RevokeSession(ctx, sessionID); err != nil {
 		return fmt.Errorf("failed to revoke session: %w", err)
 	}
 
-	fmt.Printf("Session %s revoked\n", sessionID)
-	return nil
+	return printItem(revokedView{ID: sessionID, Status: "revoked"}, func(w io.Writer, v revokedView) {
+		fmt.Fprintf(w, "Session %s revoked\n", v.ID)
+	})
 }
+
+// revokedView is the output of revoking a session or token.
+type revokedView struct {
+	ID     string `json:"id" yaml:"id"`
+	Status string `json:"status" yaml:"status"`
+}
+
+func (v revokedView) id() string { return v.ID }
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// revokeConcurrency bounds how many sessions a bulk revoke revokes at once.
+const revokeConcurrency = 8
+
+var (
+	revokeAll       bool
+	revokeLabels    []string
+	revokeSelectors []string
+	revokeLevel     string
+	revokeCreatedBy string
+	revokeMine      bool
+	revokeOlderThan time.Duration
+)
+
+func init() {
+	sessionRevokeCmd.Flags().BoolVar(&revokeAll, "all", false, "Revoke every active session")
+	sessionRevokeCmd.Flags().StringArrayVar(&revokeLabels, "label", nil, "Revoke sessions with this label, key=value (can be repeated)")
+	sessionRevokeCmd.Flags().StringArrayVar(&revokeSelectors, "selector", nil, "Revoke sessions whose selector has key=value, e.g. user=u123 (can be repeated)")
+	sessionRevokeCmd.Flags().StringVar(&revokeLevel, "level", "", "Revoke sessions at this level (debug or trace)")
+	sessionRevokeCmd.Flags().StringVar(&revokeCreatedBy, "created-by", "", "Revoke sessions created by this identity, or me")
+	sessionRevokeCmd.Flags().BoolVar(&revokeMine, "mine", false, "Same as --created-by me")
+	sessionRevokeCmd.Flags().DurationVar(&revokeOlderThan, "older-than", 0, "Revoke sessions created more than this long ago (sessions without a creation time are skipped)")
+}
+
+// bulkRevokeRequested reports whether any bulk revoke flag was given.
+func bulkRevokeRequested() bool {
+	return revokeAll || len(revokeLabels) > 0 || len(revokeSelectors) > 0 ||
+		revokeLevel != "" || revokeCreatedBy != "" || revokeMine || revokeOlderThan != 0
+}
+
+// newRevokeFilter builds the filter from the bulk revoke flags. identities
+// resolves --created-by me and --mine.
+func newRevokeFilter(now time.Time, identities func() ([]string, error)) (sessionFilter, error) {
+	var f sessionFilter
+	if revokeAll && (len(revokeLabels) > 0 || len(revokeSelectors) > 0 || revokeLevel != "" || revokeCreatedBy != "" || revokeMine || revokeOlderThan != 0) {
+		return f, usageError("--all cannot be combined with filters")
+	}
+	createdBy := revokeCreatedBy
+	if revokeMine {
+		if createdBy != "" && createdBy != "me" {
+			return f, usageError("--mine cannot be combined with --created-by %s", createdBy)
+		}
+		createdBy = "me"
+	}
+
+	labels, err := parseLabels(revokeLabels)
+	if err != nil {
+		return f, err
+	}
+	f.labels = labelEquals(labels)
+
+	if err := parseSelectorFlags(revokeSelectors, &f.selector); err != nil {
+		return f, err
+	}
+
+	if err := validateLevel(revokeLevel); err != nil {
+		return f, err
+	}
+	f.level = revokeLevel
+
+	switch createdBy {
+	case "":
+	case "me":
+		ids, err := identities()
+		if err != nil {
+			return f, err
+		}
+		f.createdBy = ids
+	default:
+		f.createdBy = []string{createdBy}
+	}
+
+	if revokeOlderThan < 0 {
+		return f, usageError("--older-than must be positive, got %s", revokeOlderThan)
+	}
+	if revokeOlderThan > 0 {
+		f.before = now.Add(-revokeOlderThan)
+	}
+	return f, nil
+}
+
+// revokeResultView is the outcome of revoking one session in bulk.
+type revokeResultView struct {
+	ID     string `json:"id" yaml:"id"`
+	Status string `json:"status" yaml:"status"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+
+	err error
+}
+
+func (v revokeResultView) id() string { return v.ID }
+
+var revokeResultColumns = []column[revokeResultView]{
+	{Header: "ID", Value: func(r revokeResultView) string { return r.ID }},
+	{Header: "RESULT", Value: func(r revokeResultView) string { return r.Status }, Color: func(r revokeResultView) string {
+		if r.err != nil {
+			return colorRed
+		}
+		return colorGreen
+	}},
+	{Header: "ERROR", Value: func(r revokeResultView) string { return orDash(r.Error) }},
+}
+
+func runBulkRevoke(cmd *cobra.Command) error {
+	filter, err := newRevokeFilter(time.Now(), loginIdentities)
+	if err != nil {
+		return err
+	}
+	human := humanOutput()
+	if !revokeYes && !human {
+		return usageError("--yes is required to revoke several sessions with a machine-readable --output")
+	}
+
+	client, err := getClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(cmd.Context())
+	active, err := client.ListSessions(ctx, "active")
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	// Checked locally too, in case the server ignores the status filter.
+	filter.status = "active"
+	matched := filter.filter(active)
+
+	if len(matched) == 0 {
+		if human {
+			fmt.Fprintln(stdout, "No matching sessions.")
+			return nil
+		}
+		return printList([]revokeResultView{}, revokeResultColumns, "")
+	}
+
+	if !revokeYes {
+		if err := printList(newSessionViews(matched), sessionColumns, ""); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "\nThis will revoke %d debug session(s)\n", len(matched))
+		fmt.Fprint(stdout, "Are you sure? [y/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" && response != "yes" {
+			fmt.Fprintln(stdout, "Cancelled.")
+			return nil
+		}
+	}
+
+	results := make([]revokeResultView, len(matched))
+	sem := make(chan struct{}, revokeConcurrency)
+	var wg sync.WaitGroup
+	for i, s := range matched {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			ctx, cancel := withTimeout(cmd.Context())
+			defer cancel()
+			results[i] = revokeResultView{ID: s.ID, Status: "revoked"}
+			if err := client.RevokeSession(ctx, s.ID); err != nil {
+				results[i] = revokeResultView{ID: s.ID, Status: "failed", Error: classifyError(err).Error(), err: err}
+			}
+		})
+	}
+	wg.Wait()
+
+	if err := printList(results, revokeResultColumns, ""); err != nil {
+		return err
+	}
+
+	var firstErr error
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = r.err
+			}
+		}
+	}
+	if human {
+		fmt.Fprintf(stdout, "\nRevoked %d of %d session(s)", len(results)-failed, len(results))
+		if failed > 0 {
+			fmt.Fprintf(stdout, ", %d failed", failed)
+		}
+		fmt.Fprintln(stdout)
+	}
+	if firstErr != nil {
+		return fmt.Errorf("failed to revoke %d of %d sessions: %w", failed, len(results), firstErr)
+	}
+	return nil
+}
@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"testing"
+	"time"
+
+	"github.com/bold-minds/trek-go"
 )
 
 func TestRevokeCommandRegistration(t *testing.T) {
@@ -70,3 +73,165 @@ func TestRevokeCommandExample(t *testing.T) {
 		t.Error("revoke command long description should contain usage example")
 	}
 }
+
+// setRevokeFilters sets the bulk revoke flags for one test and clears --mine
+// and --yes.
+func setRevokeFilters(t *testing.T, all bool, labels, selectors []string, lvl, createdBy string, olderThan time.Duration) {
+	t.Helper()
+	oldAll, oldLabels, oldSelectors := revokeAll, revokeLabels, revokeSelectors
+	oldLevel, oldCreatedBy, oldMine := revokeLevel, revokeCreatedBy, revokeMine
+	oldOlderThan, oldYes := revokeOlderThan, revokeYes
+	t.Cleanup(func() {
+		revokeAll, revokeLabels, revokeSelectors = oldAll, oldLabels, oldSelectors
+		revokeLevel, revokeCreatedBy, revokeMine = oldLevel, oldCreatedBy, oldMine
+		revokeOlderThan, revokeYes = oldOlderThan, oldYes
+	})
+	revokeAll, revokeLabels, revokeSelectors = all, labels, selectors
+	revokeLevel, revokeCreatedBy, revokeMine = lvl, createdBy, false
+	revokeOlderThan, revokeYes = olderThan, false
+}
+
+func TestSessionFilter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	session := trek.Session{
+		ID:        "sess_1",
+		Selector:  trek.Selector{UserID: "u123", Route: "/api/*", Custom: map[string]string{"region": "eu"}},
+		Level:     trek.LevelTrace,
+		Labels:    map[string]string{"team": "payments", "ticket": "PAY-1"},
+		CreatedBy: "dev@example.com",
+		CreatedAt: now.Add(-2 * time.Hour),
+	}
+	me := func() ([]string, error) { return []string{"dev@example.com", "user_1"}, nil }
+
+	tests := []struct {
+		name      string
+		all       bool
+		labels    []string
+		selectors []string
+		level     string
+		createdBy string
+		mine      bool
+		olderThan time.Duration
+		want      bool
+	}{
+		{name: "all", all: true, want: true},
+		{name: "label", labels: []string{"team=payments"}, want: true},
+		{name: "two labels, one differs", labels: []string{"team=payments", "ticket=PAY-2"}, want: false},
+		{name: "selector user", selectors: []string{"user=u123"}, want: true},
+		{name: "selector user_id", selectors: []string{"user_id=u999"}, want: false},
+		{name: "selector custom", selectors: []string{"region=eu", "route=/api/*"}, want: true},
+		{name: "level", level: "debug", want: false},
+		{name: "created by me", createdBy: "me", want: true},
+		{name: "created by other", createdBy: "ops@example.com", want: false},
+		{name: "mine", mine: true, want: true},
+		{name: "mine and created by me", createdBy: "me", mine: true, want: true},
+		{name: "older than", olderThan: time.Hour, want: true},
+		{name: "not old enough", olderThan: 3 * time.Hour, want: false},
+		{name: "combined", labels: []string{"team=payments"}, level: "trace", olderThan: time.Hour, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRevokeFilters(t, tt.all, tt.labels, tt.selectors, tt.level, tt.createdBy, tt.olderThan)
+			revokeMine = tt.mine
+			f, err := newRevokeFilter(now, me)
+			if err != nil {
+				t.Fatalf("newRevokeFilter() error = %v", err)
+			}
+			if got := f.matches(session); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	setRevokeFilters(t, false, nil, nil, "", "", time.Hour)
+	f, err := newRevokeFilter(now, me)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noCreatedAt := session
+	noCreatedAt.CreatedAt = time.Time{}
+	if f.matches(noCreatedAt) {
+		t.Error("--older-than matched a session without a creation time")
+	}
+}
+
+func TestNewRevokeFilterErrors(t *testing.T) {
+	noLogin := func() ([]string, error) { return nil, authError("not logged in") }
+
+	tests := []struct {
+		name      string
+		all       bool
+		labels    []string
+		selectors []string
+		level     string
+		createdBy string
+		mine      bool
+		olderThan time.Duration
+		wantExit  int
+	}{
+		{name: "all with filter", all: true, level: "debug", wantExit: exitUsage},
+		{name: "bad label", labels: []string{"team"}, wantExit: exitUsage},
+		{name: "bad selector", selectors: []string{"user="}, wantExit: exitUsage},
+		{name: "bad level", level: "info", wantExit: exitUsage},
+		{name: "negative age", olderThan: -time.Hour, wantExit: exitUsage},
+		{name: "all with mine", all: true, mine: true, wantExit: exitUsage},
+		{name: "mine and created by other", createdBy: "ops@example.com", mine: true, wantExit: exitUsage},
+		{name: "me without login", createdBy: "me", wantExit: exitAuth},
+		{name: "mine without login", mine: true, wantExit: exitAuth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRevokeFilters(t, tt.all, tt.labels, tt.selectors, tt.level, tt.createdBy, tt.olderThan)
+			revokeMine = tt.mine
+			_, err := newRevokeFilter(time.Now(), noLogin)
+			if got := classifyError(err); err == nil || got.ExitCode != tt.wantExit {
+				t.Errorf("newRevokeFilter() error = %v, want exit code %d", err, tt.wantExit)
+			}
+		})
+	}
+}
+
+func TestBulkRevokeAgainstDevServer(t *testing.T) {
+	srv := startDevServer(t, trek.Policy{})
+	buf := captureOutput(t, outputJSON)
+	client, err := getClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []trek.CreateSessionRequest{
+		{Selector: trek.Selector{UserID: "u1"}, Level: trek.LevelDebug, TTLSeconds: 600, Labels: map[string]string{"team": "payments"}},
+		{Selector: trek.Selector{UserID: "u2"}, Level: trek.LevelTrace, TTLSeconds: 600, Labels: map[string]string{"team": "payments"}},
+		{Selector: trek.Selector{UserID: "u3"}, Level: trek.LevelDebug, TTLSeconds: 600, Labels: map[string]string{"team": "search"}},
+	} {
+		if _, err := client.CreateSession(t.Context(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	setRevokeFilters(t, false, []string{"team=payments"}, nil, "", "", 0)
+	revokeSessionID = ""
+	if err := runRevoke(sessionRevokeCmd, nil); err == nil || !contains(err.Error(), "--yes is required") {
+		t.Fatalf("bulk revoke without --yes in JSON mode = %v, want a --yes error", err)
+	}
+
+	revokeYes = true
+	if err := runRevoke(sessionRevokeCmd, nil); err != nil {
+		t.Fatalf("bulk revoke: %v", err)
+	}
+	var results listView[revokeResultView]
+	decodeOutput(t, buf, &results)
+	if len(results.Items) != 2 || results.Items[0].Status != "revoked" || results.Items[1].Status != "revoked" {
+		t.Fatalf("results = %+v, want two revoked sessions", results.Items)
+	}
+	for _, s := range srv.sessions {
+		if s.revoked != (s.Labels["team"] == "payments") {
+			t.Errorf("session %s revoked = %v, want only team=payments revoked", s.ID, s.revoked)
+		}
+	}
+
+	if err := runRevoke(sessionRevokeCmd, []string{"sess_1"}); err == nil || !contains(err.Error(), "not both") {
+		t.Errorf("revoke with an ID and filters = %v, want a usage error", err)
+	}
+}
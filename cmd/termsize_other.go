@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package cmd
+
+import "os"
+
+// terminalSize is unknown on this platform; COLUMNS still applies.
+func terminalSize(f *os.File) int {
+	return 0
+}
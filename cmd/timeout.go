@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultTimeout bounds each API request unless --timeout, TREK_TIMEOUT or
+// the timeout config key says otherwise.
+const defaultTimeout = 30 * time.Second
+
+// requestTimeout is the parsed timeout; 0 means requests never time out.
+var requestTimeout = defaultTimeout
+
+func parseTimeout(s string) (time.Duration, error) {
+	if s == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.New("expected a duration such as 30s or 2m, or 0 for none")
+	}
+	if d < 0 {
+		return 0, errors.New("must not be negative")
+	}
+	return d, nil
+}
+
+func validateTimeout(s string) error {
+	_, err := parseTimeout(s)
+	return err
+}
+
+// withTimeout bounds ctx by the request timeout. Pass cmd.Context() so
+// Ctrl+C still cancels the request; it is nil when a command runs outside
+// Execute.
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = rootContext()
+	}
+	if requestTimeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, requestTimeout)
+}
+
+// rootContext is the context for work done outside a command's RunE, such
+// as refreshing login credentials while building the client.
+func rootContext() context.Context {
+	if ctx := rootCmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
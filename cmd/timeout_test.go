@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "10s", want: 10 * time.Second},
+		{in: "2m", want: 2 * time.Minute},
+		{in: "0", want: 0},
+		{in: "0s", want: 0},
+		{in: "-5s", wantErr: true},
+		{in: "soon", wantErr: true},
+		{in: "30", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeout(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseTimeout(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	resetConfigGlobals(t)
+
+	requestTimeout = time.Minute
+	ctx, cancel := withTimeout(context.Background())
+	deadline, ok := ctx.Deadline()
+	cancel()
+	if !ok || time.Until(deadline) > time.Minute {
+		t.Errorf("deadline = %v (set %v), want within a minute", deadline, ok)
+	}
+
+	requestTimeout = 0
+	ctx, cancel = withTimeout(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("timeout 0 should not set a deadline")
+	}
+
+	// Cancelling the command's context, as Ctrl+C does, cancels the request.
+	parent, interrupt := context.WithCancel(context.Background())
+	ctx, cancel = withTimeout(parent)
+	defer cancel()
+	interrupt()
+	if ctx.Err() != context.Canceled {
+		t.Errorf("ctx.Err() = %v, want context.Canceled", ctx.Err())
+	}
+}
+
+func TestTimeoutPrecedence(t *testing.T) {
+	path := writeTestConfig(t, "timeout: 45s\n")
+
+	tests := []struct {
+		name   string
+		flag   string
+		envVar string
+		want   time.Duration
+		source string
+	}{
+		{name: "config file", want: 45 * time.Second, source: "config file " + path},
+		{name: "env", envVar: "1m", want: time.Minute, source: "TREK_TIMEOUT"},
+		{name: "flag", flag: "5s", envVar: "1m", want: 5 * time.Second, source: "--timeout flag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetConfigGlobals(t)
+			t.Setenv("TREK_TIMEOUT", tt.envVar)
+			captureOutput(t, outputTable)
+			cfgFile, timeout = path, tt.flag
+
+			initConfig()
+			if err := preRun(configViewCmd, nil); err != nil {
+				t.Fatalf("preRun() error: %v", err)
+			}
+			if requestTimeout != tt.want || configSources["timeout"] != tt.source {
+				t.Errorf("timeout = %v from %q, want %v from %q", requestTimeout, configSources["timeout"], tt.want, tt.source)
+			}
+		})
+	}
+}
+
+func TestInvalidTimeoutIsUsageError(t *testing.T) {
+	resetConfigGlobals(t)
+	captureOutput(t, outputTable)
+	timeout = "forever"
+	configSources["timeout"] = "--timeout flag"
+
+	err := preRun(configViewCmd, nil)
+	if err == nil || !contains(err.Error(), "--timeout flag") {
+		t.Fatalf("preRun() error = %v, want invalid timeout", err)
+	}
+	if classifyError(err).ExitCode != exitUsage {
+		t.Errorf("exit code = %d, want %d", classifyError(err).ExitCode, exitUsage)
+	}
+}
@@ -1,10 +1,8 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"os"
-	"text/tabwriter"
+	"io"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -21,7 +19,7 @@ var tokensCreateCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name, _ := cmd.Flags().GetString("name")
 		if name == "" {
-			return fmt.Errorf("--name is required")
+			return usageError("--name is required")
 		}
 
 		client, err := getClient()
@@ -29,7 +27,7 @@ var tokensCreateCmd = &cobra.Command{
 			return err
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := withTimeout(cmd.Context())
 		defer cancel()
 
 		resp, err := client.CreateToken(ctx, name)
@@ -37,13 +35,19 @@ var tokensCreateCmd = &cobra.Command{
 			return fmt.Errorf("create token failed: %w", err)
 		}
 
-		fmt.Printf("Token created successfully!\n\n")
-		fmt.Printf("  ID:    %s\n", resp.ID)
-		fmt.Printf("  Name:  %s\n", resp.Name)
-		fmt.Printf("  Token: %s\n\n", resp.Token)
-		fmt.Printf("⚠️  Save this token now - it cannot be retrieved later!\n")
-
-		return nil
+		// The secret is the point of this command, so --quiet prints the
+		// token rather than its ID.
+		if quietMode && humanOutput() {
+			fmt.Fprintln(stdout, resp.Token)
+			return nil
+		}
+		return printItem(createdTokenView(*resp), func(w io.Writer, t createdTokenView) {
+			fmt.Fprintf(w, "Token created successfully!\n\n")
+			fmt.Fprintf(w, "  ID:    %s\n", t.ID)
+			fmt.Fprintf(w, "  Name:  %s\n", t.Name)
+			fmt.Fprintf(w, "  Token: %s\n\n", t.Token)
+			fmt.Fprintf(w, "⚠️  Save this token now - it cannot be retrieved later!\n")
+		})
 	},
 }
 
@@ -56,7 +60,7 @@ var tokensListCmd = &cobra.Command{
 			return err
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := withTimeout(cmd.Context())
 		defer cancel()
 
 		tokens, err := client.ListTokens(ctx)
@@ -64,23 +68,11 @@ var tokensListCmd = &cobra.Command{
 			return fmt.Errorf("list tokens failed: %w", err)
 		}
 
-		if len(tokens) == 0 {
-			fmt.Println("No tokens found")
-			return nil
-		}
-
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tNAME\tCREATED")
-		for _, t := range tokens {
-			fmt.Fprintf(w, "%s\t%s\t%s\n",
-				t.ID,
-				t.Name,
-				t.CreatedAt.Format("2006-01-02 15:04"),
-			)
+		views := make([]tokenView, len(tokens))
+		for i, t := range tokens {
+			views[i] = tokenView(t)
 		}
-		w.Flush()
-
-		return nil
+		return printList(views, tokenColumns, "No tokens found")
 	},
 }
 
@@ -90,7 +82,7 @@ var tokensRevokeCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		tokenID, _ := cmd.Flags().GetString("id")
 		if tokenID == "" {
-			return fmt.Errorf("--id is required")
+			return usageError("--id is required")
 		}
 
 		client, err := getClient()
@@ -98,18 +90,41 @@ var tokensRevokeCmd = &cobra.Command{
 			return err
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := withTimeout(cmd.Context())
 		defer cancel()
 
 		if err := client.RevokeToken(ctx, tokenID); err != nil {
 			return fmt.Errorf("revoke token failed: %w", err)
 		}
 
-		fmt.Printf("Token %s revoked\n", tokenID)
-		return nil
+		return printItem(revokedView{ID: tokenID, Status: "revoked"}, func(w io.Writer, v revokedView) {
+			fmt.Fprintf(w, "Token %s revoked\n", v.ID)
+		})
 	},
 }
 
+type tokenView struct {
+	ID        string    `json:"id" yaml:"id"`
+	Name      string    `json:"name" yaml:"name"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+func (v tokenView) id() string { return v.ID }
+
+var tokenColumns = []column[tokenView]{
+	{Header: "ID", Value: func(t tokenView) string { return t.ID }},
+	{Header: "NAME", Value: func(t tokenView) string { return t.Name }},
+	{Header: "CREATED", Value: func(t tokenView) string { return t.CreatedAt.Format("2006-01-02 15:04") }},
+}
+
+type createdTokenView struct {
+	ID    string `json:"id" yaml:"id"`
+	Name  string `json:"name" yaml:"name"`
+	Token string `json:"token" yaml:"token"`
+}
+
+func (v createdTokenView) id() string { return v.ID }
+
 func init() {
 	rootCmd.AddCommand(tokensCmd)
 